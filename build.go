@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// buildInfoCollector maps the version of the namenode, the identity of its
+// cluster and its start time from NameNodeInfo.
+var buildInfoCollector = &beanCollector{
+	bean:       nameNodeInfoBean,
+	attributes: []string{"Version", "CompileInfo", "ClusterId", "BlockPoolId", "NNStartedTimeInMillis"},
+	collect:    collectBuildInfo,
+}
+
+func collectBuildInfo(e *Exporter, bean jmxBean, ch chan<- prometheus.Metric) {
+	var (
+		info = prometheus.NewDesc(
+			prometheus.BuildFQName(e.module.name, "", "build_info"),
+			"Version of the namenode and identity of its cluster and block pool, always 1.",
+			[]string{"version", "revision", "compile_info", "cluster_id", "block_pool_id"},
+			e.constLabels,
+		)
+		started = prometheus.NewDesc(
+			prometheus.BuildFQName(e.module.name, "", "start_time_seconds"),
+			"Start time of the namenode since epoch in seconds.",
+			nil,
+			e.constLabels,
+		)
+	)
+
+	// The info metric is delivered even if some attributes are missing.
+	// Version is "<version>, r<revision>".
+	version, _ := bean.text("Version")
+	revision := ""
+	if i := strings.Index(version, ", r"); i >= 0 {
+		version, revision = version[:i], version[i+len(", r"):]
+	}
+	compileInfo, _ := bean.text("CompileInfo")
+	clusterID, _ := bean.text("ClusterId")
+	blockPoolID, _ := bean.text("BlockPoolId")
+	ch <- prometheus.MustNewConstMetric(info, prometheus.GaugeValue, 1, version, revision, compileInfo, clusterID, blockPoolID)
+
+	// NNStartedTimeInMillis is missing before Hadoop 2.8, whose NNStarted
+	// date lacks a usable time zone; jvm_start_time_seconds covers these.
+	if ms, err := bean.number("NNStartedTimeInMillis"); err == nil {
+		ch <- prometheus.MustNewConstMetric(started, prometheus.GaugeValue, ms/1000)
+	} else {
+		reportAttributeError(bean.name(), "NNStartedTimeInMillis", err)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func collectTestBuildInfo(t *testing.T, bean jmxBean) map[string]float64 {
+	e := &Exporter{module: modules["namenode"]}
+	return collectTestMetrics(t, func(ch chan<- prometheus.Metric) {
+		collectBuildInfo(e, bean, ch)
+	})
+}
+
+func TestCollectBuildInfo(t *testing.T) {
+	got := collectTestBuildInfo(t, jmxBean{
+		"name":                  nameNodeInfoBean,
+		"Version":               "3.3.6, r1be78238728da9266a4f88195058f08fd012bf9c",
+		"CompileInfo":           "2023-06-18T08:22Z by ubuntu from (HEAD detached at release-3.3.6-RC1)",
+		"ClusterId":             "CID-0d1e8a9c-7c1e-4b8e-9c39-2b3a4f5e6d7c",
+		"BlockPoolId":           "BP-1043557891-10.0.0.1-1700000000000",
+		"NNStarted":             "Tue Nov 14 22:13:20 UTC 2023",
+		"NNStartedTimeInMillis": 1700000000000.0,
+	})
+	want := map[string]float64{
+		`namenode_build_info{block_pool_id="BP-1043557891-10.0.0.1-1700000000000",cluster_id="CID-0d1e8a9c-7c1e-4b8e-9c39-2b3a4f5e6d7c",compile_info="2023-06-18T08:22Z by ubuntu from (HEAD detached at release-3.3.6-RC1)",revision="1be78238728da9266a4f88195058f08fd012bf9c",version="3.3.6"}`: 1,
+		`namenode_start_time_seconds{}`: 1700000000,
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// TestCollectBuildInfoWithoutStartTime covers namenodes before Hadoop 2.8,
+// whose NNStarted date is not used as its time zone is ambiguous.
+func TestCollectBuildInfoWithoutStartTime(t *testing.T) {
+	const bean = "Hadoop:service=NameNode,name=TestCollectBuildInfoWithoutStartTime"
+	before := attributeErrorCount(bean, "NNStartedTimeInMillis", reasonMissing)
+	got := collectTestBuildInfo(t, jmxBean{"name": bean, "Version": "2.7.7, rc1aad84bd27cd79c3d1a7dd58202a8c3ee1ed3ac", "NNStarted": "Tue Nov 14 23:13:20 CET 2023"})
+	if _, ok := got[`namenode_start_time_seconds{}`]; ok || len(got) != 1 {
+		t.Errorf("got %v, want the build info only", got)
+	}
+	if n := attributeErrorCount(bean, "NNStartedTimeInMillis", reasonMissing) - before; n != 1 {
+		t.Errorf("counted %v errors, want 1", n)
+	}
+}
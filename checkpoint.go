@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const fsNamesystemBean = "Hadoop:service=NameNode,name=FSNamesystem"
+
+// checkpointAgeCollector derives the age of the last checkpoint from the
+// LastCheckpointTime attribute of FSNamesystem, so that a namenode which
+// stopped checkpointing can be alerted on without comparing clocks in
+// queries. The age is relative to the clock of the exporter.
+var checkpointAgeCollector = &beanCollector{
+	bean: fsNamesystemBean,
+	// LastWrittenTransactionId is also fetched for haPairCollector, whatever
+	// the rules.
+	attributes: []string{"LastCheckpointTime", "LastWrittenTransactionId"},
+	collect:    collectCheckpointAge,
+}
+
+func collectCheckpointAge(e *Exporter, bean jmxBean, ch chan<- prometheus.Metric) {
+	ms, err := bean.number("LastCheckpointTime")
+	if err != nil {
+		reportAttributeError(bean.name(), "LastCheckpointTime", err)
+		return
+	}
+	if ms <= 0 {
+		// No checkpoint was taken or loaded yet.
+		return
+	}
+	age := prometheus.NewDesc(
+		prometheus.BuildFQName(e.module.name, "checkpoint", "age_seconds"),
+		"Number of seconds since the last checkpoint of the namespace.",
+		nil,
+		e.constLabels,
+	)
+	last := time.Unix(0, int64(ms)*int64(time.Millisecond))
+	ch <- prometheus.MustNewConstMetric(age, prometheus.GaugeValue, time.Since(last).Seconds())
+}
+
+// journalTransactionsCollector maps the JournalTransactionInfo attribute of
+// NameNodeInfo, the only one reporting the last transaction a standby
+// namenode applied from the shared edits.
+var journalTransactionsCollector = &beanCollector{
+	bean:       nameNodeInfoBean,
+	attributes: []string{"JournalTransactionInfo"},
+	collect:    collectJournalTransactions,
+}
+
+func collectJournalTransactions(e *Exporter, bean jmxBean, ch chan<- prometheus.Metric) {
+	txID, err := lastAppliedOrWrittenTxID(bean)
+	if err != nil {
+		reportAttributeError(bean.name(), "JournalTransactionInfo", err)
+		return
+	}
+	applied := prometheus.NewDesc(
+		prometheus.BuildFQName(e.module.name, "", "last_applied_or_written_transaction_id"),
+		"Id of the last transaction applied from the shared edits by a standby namenode, or written by the active one.",
+		nil,
+		e.constLabels,
+	)
+	ch <- prometheus.MustNewConstMetric(applied, prometheus.GaugeValue, txID)
+}
+
+// lastAppliedOrWrittenTxID reads LastAppliedOrWrittenTxId from the
+// JournalTransactionInfo attribute, a JSON document with string values.
+func lastAppliedOrWrittenTxID(bean jmxBean) (float64, error) {
+	info, err := bean.text("JournalTransactionInfo")
+	if err != nil {
+		return 0, err
+	}
+	var txIDs map[string]string
+	if err := json.Unmarshal([]byte(info), &txIDs); err != nil {
+		return 0, &attributeError{reason: reasonInvalidValue, value: info}
+	}
+	txID, ok := txIDs["LastAppliedOrWrittenTxId"]
+	if !ok {
+		return 0, &attributeError{reason: reasonMissing}
+	}
+	return decodeNumber(txID)
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// datanodeRules are the mapping rules of the datanode module used when the
+// rules file doesn't override them.
+const datanodeRules = `
+rules:
+  - bean: 'java\.lang:type=Runtime'
+    attribute: Uptime
+    name: uptime_seconds
+    help: Number of seconds since the datanode started.
+    type: gauge
+    scale: 0.001
+
+  # dataset metrics, the bean is named FSDatasetState-<storage id> before Hadoop 3
+  - bean: 'Hadoop:service=DataNode,name=FSDatasetState(?:-.+)?'
+    attribute: Capacity
+    name: capacity_bytes
+    help: Configured capacity of the datanode volumes in bytes.
+    type: gauge
+  - bean: 'Hadoop:service=DataNode,name=FSDatasetState(?:-.+)?'
+    attribute: DfsUsed
+    name: dfs_used_bytes
+    help: Space used by DFS blocks in bytes.
+    type: gauge
+  - bean: 'Hadoop:service=DataNode,name=FSDatasetState(?:-.+)?'
+    attribute: Remaining
+    name: remaining_bytes
+    help: Remaining space for DFS blocks in bytes.
+    type: gauge
+  - bean: 'Hadoop:service=DataNode,name=FSDatasetState(?:-.+)?'
+    attribute: NumFailedVolumes
+    name: failed_volumes
+    help: Number of failed volumes.
+    type: gauge
+  - bean: 'Hadoop:service=DataNode,name=FSDatasetState(?:-.+)?'
+    attribute: EstimatedCapacityLostTotal
+    name: capacity_lost_bytes
+    help: Estimated capacity lost to failed volumes in bytes.
+    type: gauge
+  - bean: 'Hadoop:service=DataNode,name=FSDatasetState(?:-.+)?'
+    attribute: LastVolumeFailureDate
+    name: last_volume_failure_timestamp_seconds
+    help: Time of the last volume failure in seconds since the epoch, 0 if none failed.
+    type: gauge
+    scale: 0.001
+  - bean: 'Hadoop:service=DataNode,name=FSDatasetState(?:-.+)?'
+    attribute: CacheCapacity
+    name: cache_capacity_bytes
+    help: Configured capacity of the block cache in bytes.
+    type: gauge
+  - bean: 'Hadoop:service=DataNode,name=FSDatasetState(?:-.+)?'
+    attribute: CacheUsed
+    name: cache_used_bytes
+    help: Used capacity of the block cache in bytes.
+    type: gauge
+  - bean: 'Hadoop:service=DataNode,name=FSDatasetState(?:-.+)?'
+    attribute: NumBlocksCached
+    name: blocks_cached
+    help: Number of cached blocks.
+    type: gauge
+  - bean: 'Hadoop:service=DataNode,name=FSDatasetState(?:-.+)?'
+    attribute: NumBlocksFailedToCache
+    name: blocks_failed_to_cache_total
+    help: Number of blocks which failed to be cached.
+    type: counter
+  - bean: 'Hadoop:service=DataNode,name=FSDatasetState(?:-.+)?'
+    attribute: NumBlocksFailedToUnCache
+    name: blocks_failed_to_uncache_total
+    help: Number of blocks which failed to be uncached.
+    type: counter
+
+  # activity metrics, the bean is named DataNodeActivity-<host>-<port>
+  - bean: 'Hadoop:service=DataNode,name=DataNodeActivity-.+'
+    attribute: BytesRead
+    name: bytes_read_total
+    help: Number of bytes read from the datanode.
+    type: counter
+  - bean: 'Hadoop:service=DataNode,name=DataNodeActivity-.+'
+    attribute: BytesWritten
+    name: bytes_written_total
+    help: Number of bytes written to the datanode.
+    type: counter
+  - bean: 'Hadoop:service=DataNode,name=DataNodeActivity-.+'
+    attribute: RemoteBytesRead
+    name: remote_bytes_read_total
+    help: Number of bytes read by remote clients.
+    type: counter
+  - bean: 'Hadoop:service=DataNode,name=DataNodeActivity-.+'
+    attribute: RemoteBytesWritten
+    name: remote_bytes_written_total
+    help: Number of bytes written by remote clients.
+    type: counter
+  - bean: 'Hadoop:service=DataNode,name=DataNodeActivity-.+'
+    attribute: BlocksRead
+    name: blocks_read_total
+    help: Number of blocks read from the datanode.
+    type: counter
+  - bean: 'Hadoop:service=DataNode,name=DataNodeActivity-.+'
+    attribute: BlocksWritten
+    name: blocks_written_total
+    help: Number of blocks written to the datanode.
+    type: counter
+  - bean: 'Hadoop:service=DataNode,name=DataNodeActivity-.+'
+    attribute: BlocksReplicated
+    name: blocks_replicated_total
+    help: Number of blocks replicated to other datanodes.
+    type: counter
+  - bean: 'Hadoop:service=DataNode,name=DataNodeActivity-.+'
+    attribute: BlocksRemoved
+    name: blocks_removed_total
+    help: Number of blocks removed from the datanode.
+    type: counter
+  - bean: 'Hadoop:service=DataNode,name=DataNodeActivity-.+'
+    attribute: BlocksVerified
+    name: blocks_verified_total
+    help: Number of blocks verified by the block scanner.
+    type: counter
+  - bean: 'Hadoop:service=DataNode,name=DataNodeActivity-.+'
+    attribute: BlockVerificationFailures
+    name: block_verification_failures_total
+    help: Number of blocks which failed verification.
+    type: counter
+  - bean: 'Hadoop:service=DataNode,name=DataNodeActivity-.+'
+    attribute: ReadsFromLocalClient
+    name: client_reads_total
+    help: Number of block reads by clients.
+    type: counter
+    labels: {client: local}
+  - bean: 'Hadoop:service=DataNode,name=DataNodeActivity-.+'
+    attribute: ReadsFromRemoteClient
+    name: client_reads_total
+    help: Number of block reads by clients.
+    type: counter
+    labels: {client: remote}
+  - bean: 'Hadoop:service=DataNode,name=DataNodeActivity-.+'
+    attribute: WritesFromLocalClient
+    name: client_writes_total
+    help: Number of block writes by clients.
+    type: counter
+    labels: {client: local}
+  - bean: 'Hadoop:service=DataNode,name=DataNodeActivity-.+'
+    attribute: WritesFromRemoteClient
+    name: client_writes_total
+    help: Number of block writes by clients.
+    type: counter
+    labels: {client: remote}
+  - bean: 'Hadoop:service=DataNode,name=DataNodeActivity-.+'
+    attribute: VolumeFailures
+    name: volume_failures_total
+    help: Number of volume failures.
+    type: counter
+  - bean: 'Hadoop:service=DataNode,name=DataNodeActivity-.+'
+    attribute: DatanodeNetworkErrors
+    name: network_errors_total
+    help: Number of network errors.
+    type: counter
+  - bean: 'Hadoop:service=DataNode,name=DataNodeActivity-.+'
+    attribute: '([A-Za-z]+?)(?:Op|Nanos)?NumOps'
+    name: ops_total
+    help: Number of operations, e.g. block reads and heartbeats.
+    type: counter
+    labels: {op: $1}
+  - bean: 'Hadoop:service=DataNode,name=DataNodeActivity-.+'
+    attribute: '([A-Za-z]+?)NanosAvgTime'
+    name: op_avg_time_seconds
+    help: Average time of the operations in the last metrics interval.
+    type: gauge
+    labels: {op: $1}
+    scale: 1e-9
+  - bean: 'Hadoop:service=DataNode,name=DataNodeActivity-.+'
+    attribute: '([A-Za-z]+?)(?:Op)?AvgTime'
+    name: op_avg_time_seconds
+    help: Average time of the operations in the last metrics interval.
+    type: gauge
+    labels: {op: $1}
+    scale: 0.001
+
+  - bean: 'Hadoop:service=DataNode,name=DataNodeInfo'
+    attribute: XceiverCount
+    name: xceivers
+    help: Number of active data transfer threads.
+    type: gauge
+`
+
+const datanodeInfoBean = "Hadoop:service=DataNode,name=DataNodeInfo"
+
+// volumeInfoCollector maps the VolumeInfo attribute of DataNodeInfo, a JSON
+// document with the usage of every volume keyed by its directory.
+var volumeInfoCollector = &beanCollector{
+	bean:       datanodeInfoBean,
+	attributes: []string{"VolumeInfo"},
+	collect:    collectVolumeInfo,
+}
+
+// volumeInfo is the usage of a single datanode volume.
+type volumeInfo struct {
+	FreeSpace                *float64 `json:"freeSpace"`
+	UsedSpace                *float64 `json:"usedSpace"`
+	ReservedSpace            *float64 `json:"reservedSpace"`
+	ReservedSpaceForReplicas *float64 `json:"reservedSpaceForReplicas"`
+	NumBlocks                *float64 `json:"numBlocks"`
+}
+
+func collectVolumeInfo(e *Exporter, bean jmxBean, ch chan<- prometheus.Metric) {
+	var volumes map[string]volumeInfo
+	if !bean.decodeJSON("VolumeInfo", &volumes) {
+		return
+	}
+
+	newDesc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(e.module.name, "volume", name), help, []string{"volume"}, e.constLabels)
+	}
+	var (
+		free                = newDesc("free_bytes", "Free space of the volume in bytes.")
+		used                = newDesc("used_bytes", "Space of the volume used by DFS blocks in bytes.")
+		reserved            = newDesc("reserved_bytes", "Space of the volume reserved for non-DFS use in bytes.")
+		reservedForReplicas = newDesc("reserved_for_replicas_bytes", "Space of the volume reserved for replicas being written in bytes.")
+		blocks              = newDesc("blocks", "Number of blocks on the volume.")
+	)
+
+	dirs := make([]string, 0, len(volumes))
+	for dir := range volumes {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	for _, dir := range dirs {
+		volume := volumes[dir]
+		for _, m := range []struct {
+			desc  *prometheus.Desc
+			value *float64
+		}{
+			{free, volume.FreeSpace},
+			{used, volume.UsedSpace},
+			{reserved, volume.ReservedSpace},
+			{reservedForReplicas, volume.ReservedSpaceForReplicas},
+			{blocks, volume.NumBlocks},
+		} {
+			if m.value != nil {
+				ch <- prometheus.MustNewConstMetric(m.desc, prometheus.GaugeValue, *m.value, dir)
+			}
+		}
+	}
+}
@@ -0,0 +1,598 @@
+package main
+
+// namenodeRules are the mapping rules of the namenode module used when the
+// rules file doesn't override them. They produce the metrics the exporter has
+// always exposed.
+const namenodeRules = `
+rules:
+  # namenode server health metrics
+  - bean: 'java\.lang:type=Runtime'
+    attribute: Uptime
+    name: uptime_seconds
+    help: Number of seconds since the namenode started.
+    type: gauge
+    scale: 0.001
+  - bean: 'Hadoop:service=NameNode,name=NameNodeStatus'
+    attribute: State
+    name: state
+    help: Whether the namenode is active (1) or not (0), see ha_state for the other states.
+    type: gauge
+    value_map: {active: 1}
+    default: 0
+  - bean: 'Hadoop:service=NameNode,name=FSNamesystemState'
+    attribute: FSState
+    name: fs_operational
+    help: The filesystem state of this namenode.
+    type: gauge
+    value_map: {Operational: 1}
+    default: 0
+  - bean: 'Hadoop:service=NameNode,name=NameNodeInfo'
+    attribute: Safemode
+    name: safemode_on
+    help: The safemode state of this namenode.
+    type: gauge
+    value_map: {"": 0}
+    default: 1
+  - bean: 'Hadoop:service=NameNode,name=FSNamesystemState'
+    attribute: NumLiveDataNodes
+    name: data_nodes_live
+    help: The number of live datanodes in this DFS.
+    type: gauge
+  - bean: 'Hadoop:service=NameNode,name=FSNamesystemState'
+    attribute: NumDeadDataNodes
+    name: data_nodes_dead
+    help: The number of dead datanodes in this DFS.
+    type: gauge
+
+  # dfs capacity metrics
+  - bean: 'Hadoop:service=NameNode,name=FSNamesystemState'
+    attribute: FilesTotal
+    name: dfs_files_total
+    help: Total number of files in DFS.
+    type: gauge
+  - bean: 'Hadoop:service=NameNode,name=NameNodeInfo'
+    attribute: PercentUsed
+    name: dfs_percent_used
+    help: 'TODO(fahlke): describe this metric'
+    type: gauge
+  - bean: 'Hadoop:service=NameNode,name=NameNodeInfo'
+    attribute: PercentRemaining
+    name: dfs_percent_remaining
+    help: 'TODO(fahlke): describe this metric'
+    type: gauge
+  - bean: 'Hadoop:service=NameNode,name=FSNamesystemState'
+    attribute: CapacityTotal
+    name: dfs_capacity_bytes_total
+    help: Total configured DFS storage capacity in bytes.
+    type: gauge
+  - bean: 'Hadoop:service=NameNode,name=FSNamesystemState'
+    attribute: CapacityUsed
+    name: dfs_capacity_bytes_used
+    help: The usage of the DFS in bytes.
+    type: gauge
+  - bean: 'Hadoop:service=NameNode,name=FSNamesystemState'
+    attribute: CapacityRemaining
+    name: dfs_capacity_bytes_remaining
+    help: The remaining capacity of the DFS in bytes.
+    type: gauge
+  - bean: 'Hadoop:service=NameNode,name=NameNodeInfo'
+    attribute: NonDfsUsedSpace
+    name: dfs_non_dfs_bytes_used
+    help: Non-DFS usage in bytes.
+    type: gauge
+
+  # dfs block metrics
+  - bean: 'Hadoop:service=NameNode,name=FSNamesystem'
+    attribute: BlocksTotal
+    name: dfs_blocks_total
+    help: Total blocks in DFS.
+    type: gauge
+  - bean: 'Hadoop:service=NameNode,name=FSNamesystem'
+    attribute: UnderReplicatedBlocks
+    name: dfs_blocks_under_replicated
+    help: Under replicated blocks in DFS.
+    type: gauge
+  - bean: 'Hadoop:service=NameNode,name=FSNamesystem'
+    attribute: PendingReplicationBlocks
+    name: dfs_blocks_pending_replication
+    help: 'TODO(fahlke): describe this metric'
+    type: gauge
+  - bean: 'Hadoop:service=NameNode,name=FSNamesystem'
+    attribute: ScheduledReplicationBlocks
+    name: dfs_blocks_scheduled_replication
+    help: 'TODO(fahlke): describe this metric'
+    type: gauge
+  - bean: 'Hadoop:service=NameNode,name=FSNamesystem'
+    attribute: PostponedMisreplicatedBlocks
+    name: dfs_blocks_postponed_misreplicated
+    help: 'TODO(fahlke): describe this metric'
+    type: gauge
+  - bean: 'Hadoop:service=NameNode,name=FSNamesystem'
+    attribute: PendingDeletionBlocks
+    name: dfs_blocks_pending_deletion
+    help: 'TODO(fahlke): describe this metric'
+    type: gauge
+  - bean: 'Hadoop:service=NameNode,name=FSNamesystem'
+    attribute: MissingBlocks
+    name: dfs_blocks_missing
+    help: Missing blocks in DFS.
+    type: gauge
+  - bean: 'Hadoop:service=NameNode,name=FSNamesystem'
+    attribute: CorruptBlocks
+    name: dfs_blocks_corrupt
+    help: Corrupted blocks in DFS.
+    type: gauge
+  - bean: 'Hadoop:service=NameNode,name=FSNamesystem'
+    attribute: ExcessBlocks
+    name: dfs_blocks_excess
+    help: Excess blocks in DFS.
+    type: gauge
+  - bean: 'Hadoop:service=NameNode,name=NameNodeInfo'
+    attribute: BlockPoolUsedSpace
+    name: dfs_block_pool_bytes_used
+    help: 'TODO(fahlke): describe this metric'
+    type: gauge
+  - bean: 'Hadoop:service=NameNode,name=NameNodeInfo'
+    attribute: PercentBlockPoolUsed
+    name: dfs_block_pool_percent_used
+    help: 'TODO(fahlke): describe this metric'
+    type: gauge
+
+  # checkpoint and edit log metrics
+  - bean: 'Hadoop:service=NameNode,name=FSNamesystem'
+    attribute: LastCheckpointTime
+    name: last_checkpoint_timestamp_seconds
+    help: Time of the last checkpoint of the namespace since epoch in seconds.
+    type: gauge
+    scale: 0.001
+  - bean: 'Hadoop:service=NameNode,name=FSNamesystem'
+    attribute: TransactionsSinceLastCheckpoint
+    name: transactions_since_last_checkpoint
+    help: Number of edit log transactions since the last checkpoint.
+    type: gauge
+  - bean: 'Hadoop:service=NameNode,name=FSNamesystem'
+    attribute: TransactionsSinceLastLogRoll
+    name: transactions_since_last_log_roll
+    help: Number of edit log transactions since the last roll of the edit log.
+    type: gauge
+  - bean: 'Hadoop:service=NameNode,name=FSNamesystem'
+    attribute: LastWrittenTransactionId
+    name: last_written_transaction_id
+    help: Id of the last transaction written to the edit log.
+    type: gauge
+  - bean: 'Hadoop:service=NameNode,name=FSNamesystem'
+    attribute: MostRecentCheckpointTxId
+    name: most_recent_checkpoint_transaction_id
+    help: Id of the last transaction included in the most recent checkpoint.
+    type: gauge
+
+  # namespace operation metrics
+  - bean: 'Hadoop:service=NameNode,name=NameNodeActivity'
+    attribute: CreateFileOps
+    name: create_file_ops_total
+    help: Number of file create operations.
+    type: counter
+  - bean: 'Hadoop:service=NameNode,name=NameNodeActivity'
+    attribute: FilesCreated
+    name: files_created_total
+    help: Number of files and directories created.
+    type: counter
+  - bean: 'Hadoop:service=NameNode,name=NameNodeActivity'
+    attribute: FilesAppended
+    name: files_appended_total
+    help: Number of files appended to.
+    type: counter
+  - bean: 'Hadoop:service=NameNode,name=NameNodeActivity'
+    attribute: FilesRenamed
+    name: files_renamed_total
+    help: Number of files renamed.
+    type: counter
+  - bean: 'Hadoop:service=NameNode,name=NameNodeActivity'
+    attribute: FilesTruncated
+    name: files_truncated_total
+    help: Number of files truncated.
+    type: counter
+  - bean: 'Hadoop:service=NameNode,name=NameNodeActivity'
+    attribute: DeleteFileOps
+    name: delete_file_ops_total
+    help: Number of delete operations.
+    type: counter
+  - bean: 'Hadoop:service=NameNode,name=NameNodeActivity'
+    attribute: FilesDeleted
+    name: files_deleted_total
+    help: Number of files and directories deleted.
+    type: counter
+  - bean: 'Hadoop:service=NameNode,name=NameNodeActivity'
+    attribute: GetBlockLocations
+    name: get_block_locations_ops_total
+    help: Number of get block locations operations.
+    type: counter
+  - bean: 'Hadoop:service=NameNode,name=NameNodeActivity'
+    attribute: GetListingOps
+    name: get_listing_ops_total
+    help: Number of directory listing operations.
+    type: counter
+  - bean: 'Hadoop:service=NameNode,name=NameNodeActivity'
+    attribute: FilesInGetListingOps
+    name: files_in_get_listing_total
+    help: Number of files and directories returned by listing operations.
+    type: counter
+  - bean: 'Hadoop:service=NameNode,name=NameNodeActivity'
+    attribute: FileInfoOps
+    name: file_info_ops_total
+    help: Number of file info operations.
+    type: counter
+  - bean: 'Hadoop:service=NameNode,name=NameNodeActivity'
+    attribute: AddBlockOps
+    name: add_block_ops_total
+    help: Number of add block operations.
+    type: counter
+  - bean: 'Hadoop:service=NameNode,name=NameNodeActivity'
+    attribute: GetAdditionalDatanodeOps
+    name: get_additional_datanode_ops_total
+    help: Number of get additional datanode operations.
+    type: counter
+  - bean: 'Hadoop:service=NameNode,name=NameNodeActivity'
+    attribute: CreateSymlinkOps
+    name: create_symlink_ops_total
+    help: Number of symlink create operations.
+    type: counter
+  - bean: 'Hadoop:service=NameNode,name=NameNodeActivity'
+    attribute: GetLinkTargetOps
+    name: get_link_target_ops_total
+    help: Number of get link target operations.
+    type: counter
+  - bean: 'Hadoop:service=NameNode,name=NameNodeActivity'
+    attribute: CreateSnapshotOps
+    name: create_snapshot_ops_total
+    help: Number of snapshot create operations.
+    type: counter
+  - bean: 'Hadoop:service=NameNode,name=NameNodeActivity'
+    attribute: DeleteSnapshotOps
+    name: delete_snapshot_ops_total
+    help: Number of snapshot delete operations.
+    type: counter
+  - bean: 'Hadoop:service=NameNode,name=NameNodeActivity'
+    attribute: BlockReceivedAndDeletedOps
+    name: block_received_and_deleted_ops_total
+    help: Number of incremental block reports received from datanodes.
+    type: counter
+  - bean: 'Hadoop:service=NameNode,name=NameNodeActivity'
+    attribute: StorageBlockReportOps
+    name: storage_block_report_ops_total
+    help: Number of storage block reports received from datanodes.
+    type: counter
+  - bean: 'Hadoop:service=NameNode,name=NameNodeActivity'
+    attribute: BlockReportNumOps
+    name: block_reports_total
+    help: Number of full block reports processed.
+    type: counter
+  - bean: 'Hadoop:service=NameNode,name=NameNodeActivity'
+    attribute: BlockReportAvgTime
+    name: block_report_avg_time_seconds
+    help: Average time to process a full block report in the last metrics interval.
+    type: gauge
+    scale: 0.001
+  - bean: 'Hadoop:service=NameNode,name=NameNodeActivity'
+    attribute: TransactionsNumOps
+    name: transactions_total
+    help: Number of edit log transactions.
+    type: counter
+  - bean: 'Hadoop:service=NameNode,name=NameNodeActivity'
+    attribute: TransactionsAvgTime
+    name: transaction_avg_time_seconds
+    help: Average time of an edit log transaction in the last metrics interval.
+    type: gauge
+    scale: 0.001
+  - bean: 'Hadoop:service=NameNode,name=NameNodeActivity'
+    attribute: SyncsNumOps
+    name: edit_log_syncs_total
+    help: Number of edit log syncs.
+    type: counter
+  - bean: 'Hadoop:service=NameNode,name=NameNodeActivity'
+    attribute: SyncsAvgTime
+    name: edit_log_sync_avg_time_seconds
+    help: Average time of an edit log sync in the last metrics interval.
+    type: gauge
+    scale: 0.001
+  - bean: 'Hadoop:service=NameNode,name=NameNodeActivity'
+    attribute: TransactionsBatchedInSync
+    name: transactions_batched_in_sync_total
+    help: Number of edit log transactions batched into a sync of another transaction.
+    type: counter
+  - bean: 'Hadoop:service=NameNode,name=NameNodeActivity'
+    attribute: SafeModeTime
+    name: safe_mode_time_seconds
+    help: Time the namenode spent in safemode during startup.
+    type: gauge
+    scale: 0.001
+  - bean: 'Hadoop:service=NameNode,name=NameNodeActivity'
+    attribute: FsImageLoadTime
+    name: fsimage_load_time_seconds
+    help: Time the namenode took to load the fsimage during startup.
+    type: gauge
+    scale: 0.001
+
+  # rpc server metrics, the bean of each RPC server is named RpcActivityForPort<port>
+  - bean: 'Hadoop:service=NameNode,name=RpcActivityForPort(\d+)'
+    attribute: ReceivedBytes
+    name: rpc_received_bytes_total
+    help: Number of bytes received by the RPC server.
+    type: counter
+    labels: {port: $1}
+  - bean: 'Hadoop:service=NameNode,name=RpcActivityForPort(\d+)'
+    attribute: SentBytes
+    name: rpc_sent_bytes_total
+    help: Number of bytes sent by the RPC server.
+    type: counter
+    labels: {port: $1}
+  - bean: 'Hadoop:service=NameNode,name=RpcActivityForPort(\d+)'
+    attribute: RpcProcessingTimeNumOps
+    name: rpc_calls_total
+    help: Number of RPC calls processed.
+    type: counter
+    labels: {port: $1}
+  - bean: 'Hadoop:service=NameNode,name=RpcActivityForPort(\d+)'
+    attribute: RpcQueueTimeAvgTime
+    name: rpc_queue_time_avg_seconds
+    help: Average time RPC calls waited in the call queue in the last metrics interval.
+    type: gauge
+    labels: {port: $1}
+    scale: 0.001
+  - bean: 'Hadoop:service=NameNode,name=RpcActivityForPort(\d+)'
+    attribute: RpcProcessingTimeAvgTime
+    name: rpc_processing_time_avg_seconds
+    help: Average time to process RPC calls in the last metrics interval.
+    type: gauge
+    labels: {port: $1}
+    scale: 0.001
+  - bean: 'Hadoop:service=NameNode,name=RpcActivityForPort(\d+)'
+    attribute: RpcLockWaitTimeAvgTime
+    name: rpc_lock_wait_time_avg_seconds
+    help: Average time RPC calls waited for the namesystem lock in the last metrics interval.
+    type: gauge
+    labels: {port: $1}
+    scale: 0.001
+  - bean: 'Hadoop:service=NameNode,name=RpcActivityForPort(\d+)'
+    attribute: CallQueueLength
+    name: rpc_call_queue_length
+    help: Number of RPC calls waiting in the call queue.
+    type: gauge
+    labels: {port: $1}
+  - bean: 'Hadoop:service=NameNode,name=RpcActivityForPort(\d+)'
+    attribute: NumOpenConnections
+    name: rpc_open_connections
+    help: Number of open RPC connections.
+    type: gauge
+    labels: {port: $1}
+  - bean: 'Hadoop:service=NameNode,name=RpcActivityForPort(\d+)'
+    attribute: NumInProcessHandler
+    name: rpc_in_process_handlers
+    help: Number of RPC handlers processing a call.
+    type: gauge
+    labels: {port: $1}
+  - bean: 'Hadoop:service=NameNode,name=RpcActivityForPort(\d+)'
+    attribute: NumDroppedConnections
+    name: rpc_dropped_connections_total
+    help: Number of RPC connections dropped.
+    type: counter
+    labels: {port: $1}
+  - bean: 'Hadoop:service=NameNode,name=RpcActivityForPort(\d+)'
+    attribute: RpcAuthenticationFailures
+    name: rpc_authentication_failures_total
+    help: Number of failed RPC authentications.
+    type: counter
+    labels: {port: $1}
+  - bean: 'Hadoop:service=NameNode,name=RpcActivityForPort(\d+)'
+    attribute: RpcAuthenticationSuccesses
+    name: rpc_authentication_successes_total
+    help: Number of successful RPC authentications.
+    type: counter
+    labels: {port: $1}
+  - bean: 'Hadoop:service=NameNode,name=RpcActivityForPort(\d+)'
+    attribute: RpcAuthorizationFailures
+    name: rpc_authorization_failures_total
+    help: Number of failed RPC authorizations.
+    type: counter
+    labels: {port: $1}
+  - bean: 'Hadoop:service=NameNode,name=RpcActivityForPort(\d+)'
+    attribute: RpcAuthorizationSuccesses
+    name: rpc_authorization_successes_total
+    help: Number of successful RPC authorizations.
+    type: counter
+    labels: {port: $1}
+  - bean: 'Hadoop:service=NameNode,name=RpcActivityForPort(\d+)'
+    attribute: RpcClientBackoff
+    name: rpc_client_backoffs_total
+    help: Number of RPC calls rejected to make clients back off.
+    type: counter
+    labels: {port: $1}
+  - bean: 'Hadoop:service=NameNode,name=RpcActivityForPort(\d+)'
+    attribute: RpcSlowCalls
+    name: rpc_slow_calls_total
+    help: Number of RPC calls considered slow.
+    type: counter
+    labels: {port: $1}
+`
+
+// jvmRules are the mapping rules of the JvmMetrics bean and the platform
+// beans of the JVM shared by all modules. {service} is replaced by the
+// service of the module.
+const jvmRules = `
+  # jvm metrics
+  - bean: 'Hadoop:service={service},name=JvmMetrics'
+    attribute: LogFatal
+    name: jvm_log_fatal
+    help: 'TODO(fahlke): describe this metric'
+    type: counter
+  - bean: 'Hadoop:service={service},name=JvmMetrics'
+    attribute: LogError
+    name: jvm_log_error
+    help: 'TODO(fahlke): describe this metric'
+    type: counter
+  - bean: 'Hadoop:service={service},name=JvmMetrics'
+    attribute: LogWarn
+    name: jvm_log_warn
+    help: 'TODO(fahlke): describe this metric'
+    type: counter
+  - bean: 'Hadoop:service={service},name=JvmMetrics'
+    attribute: LogInfo
+    name: jvm_log_info
+    help: 'TODO(fahlke): describe this metric'
+    type: counter
+  - bean: 'Hadoop:service={service},name=JvmMetrics'
+    attribute: MemHeapUsedM
+    name: jvm_mem_heap_megabytes_used
+    help: 'TODO(fahlke): describe this metric'
+    type: gauge
+  - bean: 'Hadoop:service={service},name=JvmMetrics'
+    attribute: MemHeapCommittedM
+    name: jvm_mem_heap_megabytes_committed
+    help: 'TODO(fahlke): describe this metric'
+    type: gauge
+  - bean: 'Hadoop:service={service},name=JvmMetrics'
+    attribute: MemNonHeapUsedM
+    name: jvm_mem_non_heap_megabytes_used
+    help: 'TODO(fahlke): describe this metric'
+    type: gauge
+  - bean: 'Hadoop:service={service},name=JvmMetrics'
+    attribute: MemNonHeapCommittedM
+    name: jvm_mem_non_heap_megabytes_committed
+    help: 'TODO(fahlke): describe this metric'
+    type: gauge
+  - bean: 'Hadoop:service={service},name=JvmMetrics'
+    attribute: ThreadsNew
+    name: jvm_threads_new
+    help: 'TODO(fahlke): describe this metric'
+    type: gauge
+  - bean: 'Hadoop:service={service},name=JvmMetrics'
+    attribute: ThreadsRunnable
+    name: jvm_threads_runnable
+    help: 'TODO(fahlke): describe this metric'
+    type: gauge
+  - bean: 'Hadoop:service={service},name=JvmMetrics'
+    attribute: ThreadsBlocked
+    name: jvm_threads_blocked
+    help: 'TODO(fahlke): describe this metric'
+    type: gauge
+  - bean: 'Hadoop:service={service},name=JvmMetrics'
+    attribute: ThreadsWaiting
+    name: jvm_threads_waiting
+    help: 'TODO(fahlke): describe this metric'
+    type: gauge
+  - bean: 'Hadoop:service={service},name=JvmMetrics'
+    attribute: ThreadsTimedWaiting
+    name: jvm_threads_timed_waiting
+    help: 'TODO(fahlke): describe this metric'
+    type: gauge
+  - bean: 'Hadoop:service={service},name=JvmMetrics'
+    attribute: ThreadsTerminated
+    name: jvm_threads_terminated
+    help: 'TODO(fahlke): describe this metric'
+    type: gauge
+  - bean: 'Hadoop:service={service},name=JvmMetrics'
+    attribute: 'GcCount(.+)'
+    name: jvm_gc_count_total
+    help: Number of collections of the garbage collector, as reported by JvmMetrics.
+    type: counter
+    labels: {collector: $1}
+  - bean: 'Hadoop:service={service},name=JvmMetrics'
+    attribute: 'GcTimeMillis(.+)'
+    name: jvm_gc_time_seconds_total
+    help: Time spent in collections of the garbage collector, as reported by JvmMetrics.
+    type: counter
+    scale: 0.001
+    labels: {collector: $1}
+  - bean: 'Hadoop:service={service},name=JvmMetrics'
+    attribute: GcNumWarnThresholdExceeded
+    name: jvm_gc_pauses_over_warn_threshold_total
+    help: Number of JVM pauses longer than the warn threshold of the pause monitor.
+    type: counter
+  - bean: 'Hadoop:service={service},name=JvmMetrics'
+    attribute: GcNumInfoThresholdExceeded
+    name: jvm_gc_pauses_over_info_threshold_total
+    help: Number of JVM pauses longer than the info threshold of the pause monitor.
+    type: counter
+  - bean: 'Hadoop:service={service},name=JvmMetrics'
+    attribute: GcTotalExtraSleepTime
+    name: jvm_gc_extra_sleep_seconds_total
+    help: Time the pause monitor slept longer than requested, an estimate of the time the JVM was paused.
+    type: counter
+    scale: 0.001
+
+  # garbage collector beans of the platform
+  - bean: 'java\.lang:type=GarbageCollector,name=(.+)'
+    attribute: CollectionCount
+    name: jvm_gc_collections_total
+    help: Number of collections of the garbage collector.
+    type: counter
+    labels: {collector: $1}
+  - bean: 'java\.lang:type=GarbageCollector,name=(.+)'
+    attribute: CollectionTime
+    name: jvm_gc_collection_seconds_total
+    help: Approximate accumulated time spent in collections of the garbage collector.
+    type: counter
+    scale: 0.001
+    labels: {collector: $1}
+
+  # runtime bean of the platform
+  - bean: 'java\.lang:type=Runtime'
+    attribute: StartTime
+    name: jvm_start_time_seconds
+    help: Start time of the JVM since epoch in seconds.
+    type: gauge
+    scale: 0.001
+
+  # operating system bean of the platform
+  - bean: 'java\.lang:type=OperatingSystem'
+    attribute: OpenFileDescriptorCount
+    name: os_open_file_descriptors
+    help: Number of open file descriptors of the process.
+    type: gauge
+  - bean: 'java\.lang:type=OperatingSystem'
+    attribute: MaxFileDescriptorCount
+    name: os_max_file_descriptors
+    help: Maximum number of open file descriptors of the process.
+    type: gauge
+  - bean: 'java\.lang:type=OperatingSystem'
+    attribute: ProcessCpuTime
+    name: os_process_cpu_seconds_total
+    help: CPU time used by the process.
+    type: counter
+    scale: 0.000000001
+  - bean: 'java\.lang:type=OperatingSystem'
+    attribute: ProcessCpuLoad
+    name: os_process_cpu_load
+    help: Recent CPU usage of the process between 0 and 1, negative if unavailable.
+    type: gauge
+  - bean: 'java\.lang:type=OperatingSystem'
+    attribute: SystemCpuLoad
+    name: os_system_cpu_load
+    help: Recent CPU usage of the whole system between 0 and 1, negative if unavailable.
+    type: gauge
+  - bean: 'java\.lang:type=OperatingSystem'
+    attribute: SystemLoadAverage
+    name: os_system_load_average
+    help: System load average over the last minute, negative if unavailable.
+    type: gauge
+  - bean: 'java\.lang:type=OperatingSystem'
+    attribute: AvailableProcessors
+    name: os_available_processors
+    help: Number of processors available to the JVM.
+    type: gauge
+  - bean: 'java\.lang:type=OperatingSystem'
+    attribute: FreePhysicalMemorySize
+    name: os_free_physical_memory_bytes
+    help: Free physical memory of the system.
+    type: gauge
+  - bean: 'java\.lang:type=OperatingSystem'
+    attribute: TotalPhysicalMemorySize
+    name: os_total_physical_memory_bytes
+    help: Total physical memory of the system.
+    type: gauge
+  - bean: 'java\.lang:type=OperatingSystem'
+    attribute: CommittedVirtualMemorySize
+    name: os_committed_virtual_memory_bytes
+    help: Virtual memory guaranteed to be available to the process.
+    type: gauge
+`
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+// Reasons why fetching the JMX beans failed.
+const (
+	reasonAuthentication = "authentication"
+	reasonHTTPStatus     = "http_status"
+	reasonDecode         = "decode"
+	reasonConnection     = "connection"
+)
+
+var fetchErrors = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "exporter",
+		Name:      "fetch_errors_total",
+		Help:      "Number of failed attempts to fetch the JMX beans of a Hadoop daemon.",
+	},
+	[]string{"reason"},
+)
+
+// errQueryUnsupported is returned when the JMX servlet ignores or rejects
+// the qry parameter, in which case the full dump is fetched instead.
+var errQueryUnsupported = fmt.Errorf("JMX servlet does not support the qry parameter")
+
+// jmxQuery is a request for a subset of the beans of the JMX servlet, either
+// by an object name pattern (qry) or for a single attribute (get).
+type jmxQuery struct {
+	param string
+	value string
+	// prefix is a prefix of every bean name the query can match, a bean
+	// without it reveals a servlet which ignored the query. It is empty for
+	// queries which can't be verified.
+	prefix string
+}
+
+// percentileBeans are beans named by literal rules which also carry
+// percentiles, see collectPercentiles. Their attributes depend on
+// dfs.metrics.percentiles.intervals, so they are always fetched whole.
+var percentileBeans = map[string]bool{
+	"Hadoop:service=NameNode,name=NameNodeActivity": true,
+}
+
+// jmxQueries derives the queries for the beans needed by the rules. It returns
+// nil if a rule may match beans which no query covers, so that the full dump
+// has to be fetched. With getAttributes set, beans whose rules only name
+// literal attributes are fetched attribute by attribute, which avoids
+// transferring huge attributes such as NameNodeInfo.LiveNodes.
+func jmxQueries(rules []*rule, collectors []*beanCollector, getAttributes bool) []jmxQuery {
+	var (
+		queries   []jmxQuery
+		seen      = map[string]bool{}
+		beans     []string
+		beanAttrs = map[string][]string{}
+		partial   = map[string]bool{}
+	)
+	add := func(q jmxQuery) {
+		if key := q.param + "=" + q.value; !seen[key] {
+			seen[key] = true
+			queries = append(queries, q)
+		}
+	}
+
+	for _, r := range rules {
+		switch {
+		case r.Query != "":
+			add(jmxQuery{param: "qry", value: r.Query})
+		case r.literalBean != "":
+			if _, ok := beanAttrs[r.literalBean]; !ok {
+				beans = append(beans, r.literalBean)
+				beanAttrs[r.literalBean] = nil
+			}
+			if r.literalAttribute == "" {
+				partial[r.literalBean] = true
+			} else {
+				beanAttrs[r.literalBean] = append(beanAttrs[r.literalBean], r.literalAttribute)
+			}
+		default:
+			prefix, _ := r.beanRegexp.LiteralPrefix()
+			q, ok := objectNamePrefixQuery(prefix)
+			if !ok {
+				return nil
+			}
+			add(q)
+		}
+	}
+
+	for _, c := range collectors {
+		if c.prefix {
+			q, ok := objectNamePrefixQuery(c.bean)
+			if !ok {
+				return nil
+			}
+			add(q)
+			continue
+		}
+		if _, ok := beanAttrs[c.bean]; !ok {
+			beans = append(beans, c.bean)
+		}
+		beanAttrs[c.bean] = append(beanAttrs[c.bean], c.attributes...)
+	}
+
+	for _, bean := range beans {
+		if !getAttributes || partial[bean] || percentileBeans[bean] {
+			add(jmxQuery{param: "qry", value: bean, prefix: bean})
+			continue
+		}
+		for _, attribute := range beanAttrs[bean] {
+			add(jmxQuery{param: "get", value: bean + "::" + attribute, prefix: bean})
+		}
+	}
+	return queries
+}
+
+// objectNamePrefixQuery turns a literal prefix of bean names into an object
+// name pattern, e.g. "Hadoop:service=NameNode,name=RpcActivityForPort"
+// becomes "Hadoop:service=NameNode,name=RpcActivityForPort*,*". This is only
+// possible if the prefix ends within a property value.
+func objectNamePrefixQuery(prefix string) (jmxQuery, bool) {
+	colon := strings.Index(prefix, ":")
+	if colon < 0 || strings.ContainsAny(prefix, "*?\"") {
+		return jmxQuery{}, false
+	}
+	properties := prefix[colon+1:]
+	if !strings.Contains(properties[strings.LastIndex(properties, ",")+1:], "=") {
+		return jmxQuery{}, false
+	}
+	return jmxQuery{param: "qry", value: prefix + "*,*", prefix: prefix}, true
+}
+
+// fetch retrieves the beans needed by the rules of the exporter, with
+// parallel targeted queries if possible and from the full dump otherwise.
+// Beans returned by several queries are merged by name. Once a query shows
+// that the servlet does not support them, the pending queries are skipped.
+func (e *Exporter) fetch() ([]jmxBean, error) {
+	return e.fetchQueries(e.queries)
+}
+
+// fetchQueries retrieves the beans matched by the given queries, or the full
+// dump if queries is nil or the servlet doesn't support them.
+func (e *Exporter) fetchQueries(queries []jmxQuery) ([]jmxBean, error) {
+	if queries == nil || atomic.LoadInt32(&e.target.queryUnsupported) != 0 {
+		envelope, err := e.fetchJMX(e.url)
+		if err != nil {
+			return nil, err
+		}
+		return envelope.Beans, nil
+	}
+
+	var (
+		wg      sync.WaitGroup
+		sem     = make(chan struct{}, e.concurrency)
+		results = make([][]jmxBean, len(queries))
+		errs    = make([]error, len(queries))
+	)
+	for i, q := range queries {
+		wg.Add(1)
+		go func(i int, q jmxQuery) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if atomic.LoadInt32(&e.target.queryUnsupported) != 0 {
+				return
+			}
+			results[i], errs[i] = e.query(q)
+			if errs[i] == errQueryUnsupported && atomic.CompareAndSwapInt32(&e.target.queryUnsupported, 0, 1) {
+				log.Infof("The %s %s does not support JMX queries, falling back to the full dump", e.module.name, e.url)
+			}
+		}(i, q)
+	}
+	wg.Wait()
+
+	if atomic.LoadInt32(&e.target.queryUnsupported) != 0 {
+		return e.fetchQueries(queries)
+	}
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return mergeBeans(results), nil
+}
+
+// query runs a single targeted query against the JMX servlet.
+func (e *Exporter) query(q jmxQuery) ([]jmxBean, error) {
+	u, err := url.Parse(e.url)
+	if err != nil {
+		return nil, err
+	}
+	values := u.Query()
+	values.Set(q.param, q.value)
+	u.RawQuery = values.Encode()
+
+	envelope, err := e.fetchJMX(u.String())
+	if err, ok := err.(*httpStatusError); ok {
+		switch {
+		case q.param == "get" && err.code == http.StatusNotFound:
+			// The bean or the attribute does not exist.
+			return nil, nil
+		case q.param == "qry" && (err.code == http.StatusBadRequest || err.code == http.StatusInternalServerError):
+			return nil, errQueryUnsupported
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if q.prefix != "" {
+		for _, bean := range envelope.Beans {
+			if !strings.HasPrefix(bean.name(), q.prefix) {
+				return nil, errQueryUnsupported
+			}
+		}
+	}
+	return envelope.Beans, nil
+}
+
+// httpStatusError is returned for unexpected HTTP status codes.
+type httpStatusError struct {
+	code int
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("HTTP status code %d", e.code)
+}
+
+// decodeError is returned for responses which are no valid JMX JSON.
+type decodeError struct {
+	err error
+}
+
+func (e *decodeError) Error() string {
+	return fmt.Sprintf("invalid JMX response: %s", e.err)
+}
+
+// fetchErrorReason classifies an error returned by fetch.
+func fetchErrorReason(err error) string {
+	if ue, ok := err.(*url.Error); ok {
+		err = ue.Err
+	}
+	switch err := err.(type) {
+	case *authError:
+		return reasonAuthentication
+	case *httpStatusError:
+		if err.code == http.StatusUnauthorized || err.code == http.StatusForbidden {
+			return reasonAuthentication
+		}
+		return reasonHTTPStatus
+	case *decodeError:
+		return reasonDecode
+	}
+	return reasonConnection
+}
+
+// reportFetchError logs and counts a failed fetch.
+func (e *Exporter) reportFetchError(err error) {
+	reason := fetchErrorReason(err)
+	fetchErrors.WithLabelValues(reason).Inc()
+	log.Errorf("Failed to collect metrics from %s %s (%s): %s", e.module.name, e.url, reason, err)
+}
+
+// fetchJMX requests and decodes a JMX servlet URL.
+func (e *Exporter) fetchJMX(u string) (*jmxEnvelope, error) {
+	resp, err := e.httpClient.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		ioutil.ReadAll(resp.Body) // Mindless drain body upon exit
+		resp.Body.Close()
+	}()
+
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		e.mu.Lock()
+		e.certNotAfter = resp.TLS.PeerCertificates[0].NotAfter
+		e.mu.Unlock()
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &httpStatusError{code: resp.StatusCode}
+	}
+
+	var envelope jmxEnvelope
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return nil, &decodeError{err: err}
+	}
+	return &envelope, nil
+}
+
+// mergeBeans joins the results of several queries, combining the attributes
+// of beans which were returned more than once.
+func mergeBeans(results [][]jmxBean) []jmxBean {
+	var (
+		beans  []jmxBean
+		byName = map[string]jmxBean{}
+	)
+	for _, result := range results {
+		for _, bean := range result {
+			name := bean.name()
+			merged, ok := byName[name]
+			if !ok {
+				byName[name] = bean
+				beans = append(beans, bean)
+				continue
+			}
+			for attribute, value := range bean {
+				merged[attribute] = value
+			}
+		}
+	}
+	return beans
+}
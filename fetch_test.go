@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestDefaultRulesQueries makes sure the built-in rules and collectors of
+// every module can be fetched with targeted queries. A rule whose bean
+// pattern has no usable literal prefix, e.g. an unescaped "java.lang",
+// would make every scrape fetch the full dump.
+func TestDefaultRulesQueries(t *testing.T) {
+	for name, m := range modules {
+		rules, err := parseModuleRules([]byte(m.defaultRules()))
+		if err != nil {
+			t.Fatalf("%s: can't parse default rules: %s", name, err)
+		}
+		for _, getAttributes := range []bool{false, true} {
+			if queries := jmxQueries(rules, m.defaultCollectors(), getAttributes); queries == nil {
+				t.Errorf("%s: no targeted queries (getAttributes %t)", name, getAttributes)
+			}
+		}
+	}
+}
+
+// TestPercentileBeansQueries makes sure the beans carrying percentiles are
+// fetched whole even with getAttributes, as the rules don't name the
+// percentile attributes.
+func TestPercentileBeansQueries(t *testing.T) {
+	m := modules["namenode"]
+	rules, err := parseModuleRules([]byte(m.defaultRules()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	queries := jmxQueries(rules, m.defaultCollectors(), true)
+	for bean := range percentileBeans {
+		var whole, get int
+		for _, q := range queries {
+			switch {
+			case q.param == "qry" && q.value == bean:
+				whole++
+			case q.param == "get" && strings.HasPrefix(q.value, bean+"::"):
+				get++
+			}
+		}
+		if whole != 1 || get != 0 {
+			t.Errorf("%s: got %d qry and %d get queries, want the bean fetched whole once", bean, whole, get)
+		}
+	}
+}
+
+// TestFetchQueryUnsupported checks that a servlet ignoring the qry
+// parameter is detected without running all the queries, and that the
+// exporters of later probes go straight to the full dump.
+func TestFetchQueryUnsupported(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte(`{"beans":[{"name":"Hadoop:service=NameNode,name=FSNamesystem","BlocksTotal":1}]}`))
+	}))
+	defer server.Close()
+
+	m := modules["namenode"]
+	rules, err := parseModuleRules([]byte(m.defaultRules()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := exporterOpts{
+		httpClient:  http.DefaultClient,
+		rules:       map[string][]*rule{m.name: rules},
+		concurrency: 1,
+	}
+	var states targetStates
+	for i, want := range []int32{2, 1} {
+		e := NewExporter(server.URL, m, opts, nil)
+		e.target = states.get(server.URL)
+		if len(e.queries) < 3 {
+			t.Fatalf("got %d queries, want several", len(e.queries))
+		}
+
+		atomic.StoreInt32(&requests, 0)
+		beans, err := e.fetch()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(beans) != 1 {
+			t.Errorf("fetch %d: got %d beans, want 1", i, len(beans))
+		}
+		if got := atomic.LoadInt32(&requests); got != want {
+			t.Errorf("fetch %d: got %d requests, want %d", i, got, want)
+		}
+	}
+}
+
+func TestTargetStatesExpiry(t *testing.T) {
+	var states targetStates
+	a := states.get("http://a/jmx")
+	a.queryUnsupported = 1
+	if states.get("http://a/jmx") != a {
+		t.Error("state of a was not kept")
+	}
+
+	a.lastUsed = time.Now().Add(-probeTargetTTL - time.Second)
+	if states.get("http://a/jmx") == a {
+		t.Error("expired state of a was kept")
+	}
+
+	for i := 0; i < maxProbeTargets+10; i++ {
+		states.get(fmt.Sprintf("http://host%d/jmx", i))
+	}
+	if len(states.states) != maxProbeTargets {
+		t.Errorf("got %d states, want %d", len(states.states), maxProbeTargets)
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"math"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+// haStates are the states of a namenode, as reported by the State attribute
+// of NameNodeStatus.
+var haStates = []string{"initializing", "active", "standby", "observer", "stopping"}
+
+// haStatusCollector maps the HA state of the namenode and the other
+// attributes of NameNodeStatus describing it.
+var haStatusCollector = &beanCollector{
+	bean:       nameNodeStatusBean,
+	attributes: []string{"State", "LastHATransitionTime", "NNRole", "HostAndPort", "SecurityEnabled"},
+	collect:    collectHAStatus,
+}
+
+func collectHAStatus(e *Exporter, bean jmxBean, ch chan<- prometheus.Metric) {
+	var (
+		state = prometheus.NewDesc(
+			prometheus.BuildFQName(e.module.name, "ha", "state"),
+			"Whether the namenode is in the state (1) or not (0).",
+			[]string{"state"},
+			e.constLabels,
+		)
+		transition = prometheus.NewDesc(
+			prometheus.BuildFQName(e.module.name, "ha", "last_transition_timestamp_seconds"),
+			"Time of the last HA state transition of the namenode since epoch in seconds.",
+			nil,
+			e.constLabels,
+		)
+		info = prometheus.NewDesc(
+			prometheus.BuildFQName(e.module.name, "", "status_info"),
+			"Role, RPC address and security of the namenode, always 1.",
+			[]string{"role", "host_and_port", "security_enabled"},
+			e.constLabels,
+		)
+	)
+
+	if current, err := bean.text("State"); err == nil {
+		known := false
+		for _, s := range haStates {
+			value := 0.0
+			if s == current {
+				value, known = 1, true
+			}
+			ch <- prometheus.MustNewConstMetric(state, prometheus.GaugeValue, value, s)
+		}
+		if !known {
+			reportAttributeError(bean.name(), "State", &attributeError{reason: reasonInvalidValue, value: current})
+		}
+	} else {
+		reportAttributeError(bean.name(), "State", err)
+	}
+
+	if ms, err := bean.number("LastHATransitionTime"); err == nil {
+		ch <- prometheus.MustNewConstMetric(transition, prometheus.GaugeValue, ms/1000)
+	} else {
+		reportAttributeError(bean.name(), "LastHATransitionTime", err)
+	}
+
+	// The info metric is delivered even if some attributes are missing.
+	role, _ := bean.text("NNRole")
+	hostAndPort, _ := bean.text("HostAndPort")
+	securityEnabled := ""
+	if v, err := bean.number("SecurityEnabled"); err == nil {
+		securityEnabled = "false"
+		if v != 0 {
+			securityEnabled = "true"
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(info, prometheus.GaugeValue, 1, role, hostAndPort, securityEnabled)
+}
+
+// haPairCollector compares the transactions of the namenodes of a
+// nameservice, which the exporters of the single namenodes can't as each only
+// knows its own namenode. For polled namenodes it reads the last snapshot of
+// their exporter, the others are queried for the few beans it needs on each
+// scrape.
+type haPairCollector struct {
+	nameservice string
+	exporters   []*Exporter
+	queries     []jmxQuery
+
+	lag *prometheus.Desc
+}
+
+// haPairBeans are the attributes compared by haPairCollector. The last
+// transaction applied by a standby is only reported in the
+// JournalTransactionInfo document, LastWrittenTransactionId is used if it
+// is missing.
+var haPairBeans = []*beanCollector{
+	{bean: nameNodeStatusBean, attributes: []string{"State"}},
+	{bean: fsNamesystemBean, attributes: []string{"LastWrittenTransactionId"}},
+	{bean: nameNodeInfoBean, attributes: []string{"JournalTransactionInfo"}},
+}
+
+// newHAPairCollector returns a collector for the namenodes of a nameservice,
+// given by the exporters scraping them.
+func newHAPairCollector(nameservice string, exporters []*Exporter) *haPairCollector {
+	return &haPairCollector{
+		nameservice: nameservice,
+		exporters:   exporters,
+		queries:     jmxQueries(nil, haPairBeans, true),
+		lag: prometheus.NewDesc(
+			prometheus.BuildFQName("namenode", "ha", "standby_lag_transactions"),
+			"Number of transactions written by the active namenode which the most lagging standby namenode hasn't applied yet.",
+			nil,
+			prometheus.Labels{"nameservice": nameservice},
+		),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *haPairCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.lag
+}
+
+// haTransactions is the HA state and last transaction ids of a namenode.
+type haTransactions struct {
+	state   string
+	written float64
+	applied float64
+	ok      bool
+}
+
+// Collect implements prometheus.Collector. Nothing is delivered unless an
+// active and a standby namenode could be reached.
+func (c *haPairCollector) Collect(ch chan<- prometheus.Metric) {
+	var (
+		wg      sync.WaitGroup
+		results = make([]haTransactions, len(c.exporters))
+	)
+	for i, e := range c.exporters {
+		wg.Add(1)
+		go func(i int, e *Exporter) {
+			defer wg.Done()
+			beans, polling := e.polledSnapshot()
+			if !polling {
+				var err error
+				if beans, err = e.fetchQueries(c.queries); err != nil {
+					// Reported by the exporter of the namenode as well.
+					log.Debugf("Failed to fetch the HA state of namenode %s: %s", e.url, err)
+					return
+				}
+			}
+			results[i] = readHATransactions(beans)
+		}(i, e)
+	}
+	wg.Wait()
+
+	var (
+		active       *haTransactions
+		lag          float64
+		haveStandbys bool
+	)
+	for i, r := range results {
+		if r.ok && r.state == "active" {
+			active = &results[i]
+		}
+	}
+	if active == nil {
+		return
+	}
+	for _, r := range results {
+		if !r.ok || r.state != "standby" {
+			continue
+		}
+		// The namenodes aren't read at the same instant, so a standby may
+		// seem ahead of the active namenode.
+		if l := math.Max(active.written-r.applied, 0); !haveStandbys || l > lag {
+			lag = l
+		}
+		haveStandbys = true
+	}
+	if haveStandbys {
+		ch <- prometheus.MustNewConstMetric(c.lag, prometheus.GaugeValue, lag)
+	}
+}
+
+// readHATransactions extracts the HA state and transaction ids of a namenode
+// from its beans. Invalid attributes are reported by the exporter of the
+// namenode.
+func readHATransactions(beans []jmxBean) haTransactions {
+	var (
+		t                                   haTransactions
+		haveState, haveWritten, haveApplied bool
+	)
+	for _, bean := range beans {
+		switch bean.name() {
+		case nameNodeStatusBean:
+			if state, err := bean.text("State"); err == nil {
+				t.state, haveState = state, true
+			}
+		case fsNamesystemBean:
+			if written, err := bean.number("LastWrittenTransactionId"); err == nil {
+				t.written, haveWritten = written, true
+			}
+		case nameNodeInfoBean:
+			if applied, err := lastAppliedOrWrittenTxID(bean); err == nil {
+				t.applied, haveApplied = applied, true
+			}
+		}
+	}
+	if !haveApplied {
+		t.applied = t.written
+	}
+	t.ok = haveState && haveWritten
+	return t
+}
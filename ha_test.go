@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// newTestJMXServer returns a stand-in for the JMX servlet serving the given
+// beans, which supports literal qry and get queries.
+func newTestJMXServer(beans ...jmxBean) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var result []jmxBean
+		switch qry, get := r.URL.Query().Get("qry"), r.URL.Query().Get("get"); {
+		case get != "":
+			parts := strings.SplitN(get, "::", 2)
+			for _, bean := range beans {
+				if v, ok := bean[parts[1]]; ok && bean.name() == parts[0] {
+					result = append(result, jmxBean{"name": bean.name(), parts[1]: v})
+				}
+			}
+			if result == nil {
+				http.NotFound(w, r)
+				return
+			}
+		default:
+			for _, bean := range beans {
+				if qry == "" || bean.name() == qry {
+					result = append(result, bean)
+				}
+			}
+		}
+		json.NewEncoder(w).Encode(jmxEnvelope{Beans: result})
+	}))
+}
+
+func testHABeans(state string, written float64, journalTransactionInfo string) []jmxBean {
+	return []jmxBean{
+		{"name": nameNodeStatusBean, "State": state, "NNRole": "NameNode"},
+		{"name": fsNamesystemBean, "LastWrittenTransactionId": written, "BlocksTotal": 10.0},
+		{"name": nameNodeInfoBean, "JournalTransactionInfo": journalTransactionInfo, "LiveNodes": "{}"},
+	}
+}
+
+func TestReadHATransactions(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		beans []jmxBean
+		want  haTransactions
+	}{
+		{
+			name:  "standby",
+			beans: testHABeans("standby", 1300, `{"MostRecentCheckpointTxId":"1200","LastAppliedOrWrittenTxId":"1290"}`),
+			want:  haTransactions{state: "standby", written: 1300, applied: 1290, ok: true},
+		},
+		{
+			name:  "without applied transaction",
+			beans: testHABeans("standby", 1300, `{"MostRecentCheckpointTxId":"1200"}`),
+			want:  haTransactions{state: "standby", written: 1300, applied: 1300, ok: true},
+		},
+		{
+			name:  "malformed journal transactions",
+			beans: testHABeans("active", 1300, `{"LastAppliedOrWrittenTxId":`),
+			want:  haTransactions{state: "active", written: 1300, applied: 1300, ok: true},
+		},
+		{
+			name:  "without state",
+			beans: testHABeans("standby", 1300, `{}`)[1:],
+			want:  haTransactions{written: 1300, applied: 1300},
+		},
+	} {
+		if got := readHATransactions(tc.beans); got != tc.want {
+			t.Errorf("%s: got %+v, want %+v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestHAPairCollector(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		standbys []float64
+		want     map[string]float64
+	}{
+		{"lagging standbys", []float64{1290, 1250}, map[string]float64{`namenode_ha_standby_lag_transactions{nameservice="ns1"}`: 50}},
+		// The standby was read after the active namenode wrote more.
+		{"standby ahead", []float64{1310}, map[string]float64{`namenode_ha_standby_lag_transactions{nameservice="ns1"}`: 0}},
+		{"no standby", nil, map[string]float64{}},
+	} {
+		servers := []*httptest.Server{newTestJMXServer(testHABeans("active", 1300, `{"LastAppliedOrWrittenTxId":"1300"}`)...)}
+		for _, applied := range tc.standbys {
+			info := fmt.Sprintf(`{"LastAppliedOrWrittenTxId":"%d"}`, int(applied))
+			servers = append(servers, newTestJMXServer(testHABeans("standby", 1200, info)...))
+		}
+		var exporters []*Exporter
+		for _, s := range servers {
+			exporters = append(exporters, NewExporter(s.URL, modules["namenode"], exporterOpts{httpClient: http.DefaultClient, concurrency: 2}, nil))
+		}
+
+		c := newHAPairCollector("ns1", exporters)
+		got := collectTestMetrics(t, c.Collect)
+		for _, s := range servers {
+			s.Close()
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+// TestHAPairCollectorPolling makes sure polled namenodes are read from
+// their snapshot, unless it is stale.
+func TestHAPairCollectorPolling(t *testing.T) {
+	newPolledExporter := func(state string, written float64, info string, age time.Duration) *Exporter {
+		return &Exporter{
+			url:          "http://unreachable.invalid/jmx",
+			module:       modules["namenode"],
+			target:       &targetState{},
+			polling:      true,
+			maxStaleness: time.Minute,
+			snapshot:     &jmxEnvelope{Beans: testHABeans(state, written, info)},
+			snapshotTime: time.Now().Add(-age),
+		}
+	}
+	lag := `namenode_ha_standby_lag_transactions{nameservice="ns1"}`
+	for _, tc := range []struct {
+		name string
+		age  time.Duration
+		want map[string]float64
+	}{
+		{"fresh", 10 * time.Second, map[string]float64{lag: 20}},
+		{"stale", 2 * time.Minute, map[string]float64{}},
+	} {
+		c := newHAPairCollector("ns1", []*Exporter{
+			newPolledExporter("active", 1300, `{"LastAppliedOrWrittenTxId":"1300"}`, 0),
+			newPolledExporter("standby", 1200, `{"LastAppliedOrWrittenTxId":"1280"}`, tc.age),
+		})
+		if got := collectTestMetrics(t, c.Collect); fmt.Sprint(got) != fmt.Sprint(tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestCollectJournalTransactions(t *testing.T) {
+	e := &Exporter{module: modules["namenode"]}
+	got := collectTestMetrics(t, func(ch chan<- prometheus.Metric) {
+		collectJournalTransactions(e, jmxBean{"name": nameNodeInfoBean, "JournalTransactionInfo": `{"MostRecentCheckpointTxId":"1200","LastAppliedOrWrittenTxId":"1290"}`}, ch)
+	})
+	want := map[string]float64{`namenode_last_applied_or_written_transaction_id{}`: 1290}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	const bean = "Hadoop:service=NameNode,name=TestCollectJournalTransactions"
+	for _, tc := range []struct {
+		value  interface{}
+		reason string
+	}{
+		{`{"LastAppliedOrWrittenTxId":`, reasonInvalidValue},
+		{`{"MostRecentCheckpointTxId":"1200"}`, reasonMissing},
+		{`{"LastAppliedOrWrittenTxId":"n/a"}`, reasonInvalidValue},
+		{nil, reasonNull},
+	} {
+		before := attributeErrorCount(bean, "JournalTransactionInfo", tc.reason)
+		got := collectTestMetrics(t, func(ch chan<- prometheus.Metric) {
+			collectJournalTransactions(e, jmxBean{"name": bean, "JournalTransactionInfo": tc.value}, ch)
+		})
+		if len(got) != 0 {
+			t.Errorf("%v: got %v, want no metrics", tc.value, got)
+		}
+		if n := attributeErrorCount(bean, "JournalTransactionInfo", tc.reason) - before; n != 1 {
+			t.Errorf("%v: counted %v errors with reason %q, want 1", tc.value, n, tc.reason)
+		}
+	}
+}
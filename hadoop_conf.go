@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// hadoopConfigFiles are read in order from the Hadoop configuration
+// directory, properties of later files override earlier ones.
+var hadoopConfigFiles = []string{"core-site.xml", "hdfs-site.xml"}
+
+// hadoopConfiguration holds the properties of the Hadoop XML configuration
+// files.
+type hadoopConfiguration map[string]string
+
+type xmlConfiguration struct {
+	Properties []struct {
+		Name  string `xml:"name"`
+		Value string `xml:"value"`
+	} `xml:"property"`
+}
+
+// loadHadoopConfiguration reads core-site.xml and hdfs-site.xml from the
+// given directory. Missing files are skipped, but at least one must exist.
+func loadHadoopConfiguration(dir string) (hadoopConfiguration, error) {
+	conf := hadoopConfiguration{}
+	found := false
+	for _, name := range hadoopConfigFiles {
+		path := filepath.Join(dir, name)
+		content, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("can't read hadoop configuration %q: %s", path, err)
+		}
+
+		var xc xmlConfiguration
+		if err := xml.Unmarshal(content, &xc); err != nil {
+			return nil, fmt.Errorf("can't parse hadoop configuration %q: %s", path, err)
+		}
+		for _, p := range xc.Properties {
+			conf[strings.TrimSpace(p.Name)] = strings.TrimSpace(p.Value)
+		}
+		found = true
+	}
+	if !found {
+		return nil, fmt.Errorf("no hadoop configuration files found in %q", dir)
+	}
+	return conf, nil
+}
+
+// get returns the value of the first of the given keys which is set.
+func (c hadoopConfiguration) get(keys ...string) string {
+	for _, key := range keys {
+		if value := c[key]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// list returns the comma separated values of the given key.
+func (c hadoopConfiguration) list(key string) []string {
+	var values []string
+	for _, value := range strings.Split(c[key], ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// namenodeEndpoint is a namenode JMX endpoint derived from the Hadoop
+// configuration.
+type namenodeEndpoint struct {
+	nameservice string
+	namenodeID  string
+	url         string
+	// rpcPorts maps the configured RPC ports to their role.
+	rpcPorts map[string]string
+}
+
+// namenodeEndpoints derives the JMX endpoints of all namenodes, covering
+// federated nameservices as well as HA namenodes within a nameservice. The
+// default web addresses are those of Hadoop 3.
+func (c hadoopConfiguration) namenodeEndpoints() ([]namenodeEndpoint, error) {
+	scheme, addressKey, defaultAddress := "http", "dfs.namenode.http-address", "0.0.0.0:9870"
+	switch policy := c.get("dfs.http.policy"); strings.ToUpper(policy) {
+	case "", "HTTP_ONLY", "HTTP_AND_HTTPS":
+	case "HTTPS_ONLY":
+		scheme, addressKey, defaultAddress = "https", "dfs.namenode.https-address", "0.0.0.0:9871"
+	default:
+		return nil, fmt.Errorf("unsupported dfs.http.policy %q", policy)
+	}
+
+	nameservices := c.list("dfs.internal.nameservices")
+	if len(nameservices) == 0 {
+		nameservices = c.list("dfs.nameservices")
+	}
+	if len(nameservices) == 0 {
+		address := c.get(addressKey)
+		if address == "" {
+			address = defaultAddress
+		}
+		u, err := c.jmxURL(scheme, address, c.get("dfs.namenode.rpc-address"))
+		if err != nil {
+			return nil, err
+		}
+		return []namenodeEndpoint{{url: u, rpcPorts: c.rpcPorts("")}}, nil
+	}
+
+	var endpoints []namenodeEndpoint
+	for _, ns := range nameservices {
+		namenodeIDs := c.list("dfs.ha.namenodes." + ns)
+		if len(namenodeIDs) == 0 {
+			// Federated nameservice without HA.
+			address := c.get(addressKey + "." + ns)
+			if address == "" {
+				return nil, fmt.Errorf("no %s configured for nameservice %q", addressKey, ns)
+			}
+			u, err := c.jmxURL(scheme, address, c.get("dfs.namenode.rpc-address."+ns))
+			if err != nil {
+				return nil, err
+			}
+			endpoints = append(endpoints, namenodeEndpoint{nameservice: ns, url: u, rpcPorts: c.rpcPorts("." + ns)})
+			continue
+		}
+
+		for _, nn := range namenodeIDs {
+			suffix := "." + ns + "." + nn
+			address := c.get(addressKey + suffix)
+			if address == "" {
+				return nil, fmt.Errorf("no %s configured for namenode %q of nameservice %q", addressKey, nn, ns)
+			}
+			u, err := c.jmxURL(scheme, address, c.get("dfs.namenode.rpc-address"+suffix))
+			if err != nil {
+				return nil, err
+			}
+			endpoints = append(endpoints, namenodeEndpoint{nameservice: ns, namenodeID: nn, url: u, rpcPorts: c.rpcPorts(suffix)})
+		}
+	}
+	return endpoints, nil
+}
+
+// rpcPortRoleKeys maps the roles of the namenode RPC servers to the keys of
+// their addresses.
+var rpcPortRoleKeys = []struct {
+	role string
+	key  string
+}{
+	{"client", "dfs.namenode.rpc-address"},
+	{"service", "dfs.namenode.servicerpc-address"},
+	{"lifeline", "dfs.namenode.lifeline.rpc-address"},
+}
+
+// rpcPorts returns the roles of the RPC ports configured for a namenode,
+// suffix selects the nameservice and namenode as in the address keys.
+func (c hadoopConfiguration) rpcPorts(suffix string) map[string]string {
+	ports := map[string]string{}
+	for _, r := range rpcPortRoleKeys {
+		if _, port, err := net.SplitHostPort(c.get(r.key + suffix)); err == nil {
+			ports[port] = r.role
+		}
+	}
+	return ports
+}
+
+// jmxURL builds the JMX URL of a namenode web address. A wildcard bind host
+// is replaced with the host of the namenode RPC address or fs.defaultFS.
+func (c hadoopConfiguration) jmxURL(scheme, address, rpcAddress string) (string, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", fmt.Errorf("invalid namenode web address %q: %s", address, err)
+	}
+
+	if host == "" || host == "0.0.0.0" {
+		host = ""
+		if rpcHost, _, err := net.SplitHostPort(rpcAddress); err == nil && rpcHost != "0.0.0.0" {
+			host = rpcHost
+		} else if u, err := url.Parse(c.get("fs.defaultFS", "fs.default.name")); err == nil && u.Scheme == "hdfs" {
+			host = u.Hostname()
+		}
+		if host == "" {
+			host = "localhost"
+		}
+	}
+
+	return (&url.URL{Scheme: scheme, Host: net.JoinHostPort(host, port), Path: "/jmx"}).String(), nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNamenodeEndpoints(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		conf hadoopConfiguration
+		want []namenodeEndpoint
+		err  bool
+	}{
+		{
+			name: "defaults",
+			conf: hadoopConfiguration{},
+			want: []namenodeEndpoint{{url: "http://localhost:9870/jmx", rpcPorts: map[string]string{}}},
+		},
+		{
+			name: "default address with the host of fs.defaultFS",
+			conf: hadoopConfiguration{"fs.defaultFS": "hdfs://nn.example.com:8020"},
+			want: []namenodeEndpoint{{url: "http://nn.example.com:9870/jmx", rpcPorts: map[string]string{}}},
+		},
+		{
+			name: "HTTPS_ONLY default address",
+			conf: hadoopConfiguration{"dfs.http.policy": "HTTPS_ONLY", "dfs.namenode.rpc-address": "nn.example.com:8020"},
+			want: []namenodeEndpoint{{url: "https://nn.example.com:9871/jmx", rpcPorts: map[string]string{"8020": "client"}}},
+		},
+		{
+			name: "HTTP_ONLY",
+			conf: hadoopConfiguration{
+				"dfs.http.policy":            "HTTP_ONLY",
+				"dfs.namenode.http-address":  "nn.example.com:50070",
+				"dfs.namenode.https-address": "nn.example.com:50470",
+			},
+			want: []namenodeEndpoint{{url: "http://nn.example.com:50070/jmx", rpcPorts: map[string]string{}}},
+		},
+		{
+			name: "HTTP_AND_HTTPS uses the HTTP address",
+			conf: hadoopConfiguration{
+				"dfs.http.policy":            "http_and_https",
+				"dfs.namenode.http-address":  "nn.example.com:50070",
+				"dfs.namenode.https-address": "nn.example.com:50470",
+			},
+			want: []namenodeEndpoint{{url: "http://nn.example.com:50070/jmx", rpcPorts: map[string]string{}}},
+		},
+		{
+			name: "HTTPS_ONLY",
+			conf: hadoopConfiguration{
+				"dfs.http.policy":            "HTTPS_ONLY",
+				"dfs.namenode.http-address":  "nn.example.com:50070",
+				"dfs.namenode.https-address": "nn.example.com:50470",
+			},
+			want: []namenodeEndpoint{{url: "https://nn.example.com:50470/jmx", rpcPorts: map[string]string{}}},
+		},
+		{
+			name: "unsupported policy",
+			conf: hadoopConfiguration{"dfs.http.policy": "HTTPS_MAYBE"},
+			err:  true,
+		},
+		{
+			name: "HA and federated nameservices",
+			conf: hadoopConfiguration{
+				"dfs.nameservices":                          "ns1, ns2",
+				"dfs.ha.namenodes.ns1":                      "nn1,nn2",
+				"dfs.namenode.http-address.ns1.nn1":         "nn1.example.com:9870",
+				"dfs.namenode.http-address.ns1.nn2":         "0.0.0.0:9870",
+				"dfs.namenode.rpc-address.ns1.nn1":          "nn1.example.com:8020",
+				"dfs.namenode.rpc-address.ns1.nn2":          "nn2.example.com:8020",
+				"dfs.namenode.servicerpc-address.ns1.nn2":   "nn2.example.com:8022",
+				"dfs.namenode.lifeline.rpc-address.ns1.nn2": "nn2.example.com:8050",
+				"dfs.namenode.http-address.ns2":             "nn3.example.com:9870",
+				"dfs.namenode.http-address.ns3.nn1":         "ignored.example.com:9870",
+			},
+			want: []namenodeEndpoint{
+				{nameservice: "ns1", namenodeID: "nn1", url: "http://nn1.example.com:9870/jmx", rpcPorts: map[string]string{"8020": "client"}},
+				{nameservice: "ns1", namenodeID: "nn2", url: "http://nn2.example.com:9870/jmx", rpcPorts: map[string]string{"8020": "client", "8022": "service", "8050": "lifeline"}},
+				{nameservice: "ns2", url: "http://nn3.example.com:9870/jmx", rpcPorts: map[string]string{}},
+			},
+		},
+		{
+			name: "internal nameservices take precedence",
+			conf: hadoopConfiguration{
+				"dfs.nameservices":              "ns1,remote",
+				"dfs.internal.nameservices":     "ns1",
+				"dfs.namenode.http-address.ns1": "nn1.example.com:9870",
+			},
+			want: []namenodeEndpoint{{nameservice: "ns1", url: "http://nn1.example.com:9870/jmx", rpcPorts: map[string]string{}}},
+		},
+		{
+			name: "missing address of an HA namenode",
+			conf: hadoopConfiguration{
+				"dfs.nameservices":                  "ns1",
+				"dfs.ha.namenodes.ns1":              "nn1,nn2",
+				"dfs.namenode.http-address.ns1.nn1": "nn1.example.com:9870",
+			},
+			err: true,
+		},
+		{
+			name: "missing address of a federated nameservice",
+			conf: hadoopConfiguration{"dfs.nameservices": "ns1"},
+			err:  true,
+		},
+		{
+			name: "invalid address",
+			conf: hadoopConfiguration{"dfs.namenode.http-address": "nn.example.com"},
+			err:  true,
+		},
+	} {
+		got, err := tc.conf.namenodeEndpoints()
+		if tc.err {
+			if err == nil {
+				t.Errorf("%s: got no error", tc.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %+v, want %+v", tc.name, got, tc.want)
+		}
+	}
+}
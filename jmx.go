@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+// Reasons why a bean attribute could not be turned into a metric value.
+const (
+	reasonMissing      = "missing"
+	reasonNull         = "null"
+	reasonInvalidType  = "invalid_type"
+	reasonInvalidValue = "invalid_value"
+)
+
+var attributeErrors = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "exporter",
+		Name:      "attribute_errors_total",
+		Help:      "Number of bean attributes which could not be converted to a metric value.",
+	},
+	[]string{"bean", "attribute", "reason"},
+)
+
+type jmxEnvelope struct {
+	Beans []jmxBean `json:"beans"`
+}
+
+type jmxBean map[string]interface{}
+
+// attributeError describes why a bean attribute could not be converted.
+type attributeError struct {
+	reason string
+	value  interface{}
+}
+
+func (e *attributeError) Error() string {
+	if e.reason == reasonMissing || e.reason == reasonNull {
+		return e.reason + " value"
+	}
+	return fmt.Sprintf("%s %T value %v", e.reason, e.value, e.value)
+}
+
+// name returns the object name of the bean.
+func (b jmxBean) name() string {
+	name, _ := b["name"].(string)
+	return name
+}
+
+// number decodes a numeric attribute of the bean.
+func (b jmxBean) number(attribute string) (float64, error) {
+	v, ok := b[attribute]
+	if !ok {
+		return 0, &attributeError{reason: reasonMissing}
+	}
+	return decodeNumber(v)
+}
+
+// text returns a string attribute of the bean.
+func (b jmxBean) text(attribute string) (string, error) {
+	switch v := b[attribute].(type) {
+	case string:
+		return v, nil
+	case nil:
+		if _, ok := b[attribute]; !ok {
+			return "", &attributeError{reason: reasonMissing}
+		}
+		return "", &attributeError{reason: reasonNull}
+	default:
+		return "", &attributeError{reason: reasonInvalidType, value: v}
+	}
+}
+
+// decodeJSON decodes an attribute of the bean holding a JSON document in a
+// string, as some Hadoop beans do for nested data. Failures are reported as
+// attribute errors and make it return false.
+func (b jmxBean) decodeJSON(attribute string, v interface{}) bool {
+	value, ok := b[attribute]
+	if !ok {
+		reportAttributeError(b.name(), attribute, &attributeError{reason: reasonMissing})
+		return false
+	}
+	s, ok := value.(string)
+	if !ok {
+		reportAttributeError(b.name(), attribute, &attributeError{reason: reasonInvalidType, value: value})
+		return false
+	}
+	if err := json.Unmarshal([]byte(s), v); err != nil {
+		reportAttributeError(b.name(), attribute, &attributeError{reason: reasonInvalidValue, value: value})
+		return false
+	}
+	return true
+}
+
+// decode converts an attribute of the bean holding nested JSON data, such as
+// the composite values of the platform beans, into v. Failures are reported
+// as attribute errors and make it return false.
+func (b jmxBean) decode(attribute string, v interface{}) bool {
+	value, ok := b[attribute]
+	if !ok {
+		reportAttributeError(b.name(), attribute, &attributeError{reason: reasonMissing})
+		return false
+	}
+	content, err := json.Marshal(value)
+	if err == nil {
+		err = json.Unmarshal(content, v)
+	}
+	if err != nil {
+		reportAttributeError(b.name(), attribute, &attributeError{reason: reasonInvalidType, value: value})
+		return false
+	}
+	return true
+}
+
+// decodeNumber converts a decoded JSON value into a float. Besides numbers it
+// accepts booleans and strings holding a number or a boolean, which some
+// beans use instead of native JSON types.
+func decodeNumber(v interface{}) (float64, error) {
+	switch v := v.(type) {
+	case nil:
+		return 0, &attributeError{reason: reasonNull}
+	case float64:
+		return v, nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case string:
+		s := strings.TrimSpace(v)
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f, nil
+		}
+		if b, err := strconv.ParseBool(s); err == nil {
+			return decodeNumber(b)
+		}
+		return 0, &attributeError{reason: reasonInvalidValue, value: v}
+	default:
+		return 0, &attributeError{reason: reasonInvalidType, value: v}
+	}
+}
+
+// reportAttributeError logs and counts an attribute which could not be
+// converted, so that schema changes of the beans are noticed.
+func reportAttributeError(beanName, attribute string, err error) {
+	reason := reasonInvalidValue
+	if ae, ok := err.(*attributeError); ok {
+		reason = ae.reason
+	}
+	attributeErrors.WithLabelValues(beanName, attribute, reason).Inc()
+	log.Debugf("Skipping attribute %s::%s: %s", beanName, attribute, err)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestDecodeNumber(t *testing.T) {
+	for _, tc := range []struct {
+		value  interface{}
+		want   float64
+		reason string
+	}{
+		{1.5, 1.5, ""},
+		{true, 1, ""},
+		{false, 0, ""},
+		{"42", 42, ""},
+		{" 0.25 ", 0.25, ""},
+		{"1e3", 1000, ""},
+		{"true", 1, ""},
+		{"False", 0, ""},
+		{nil, 0, reasonNull},
+		{"", 0, reasonInvalidValue},
+		{"active", 0, reasonInvalidValue},
+		{[]interface{}{1.0}, 0, reasonInvalidType},
+		{map[string]interface{}{"used": 1.0}, 0, reasonInvalidType},
+	} {
+		got, err := decodeNumber(tc.value)
+		if tc.reason == "" {
+			if err != nil || got != tc.want {
+				t.Errorf("%#v: got %v (%v), want %v", tc.value, got, err, tc.want)
+			}
+			continue
+		}
+		ae, ok := err.(*attributeError)
+		if !ok || ae.reason != tc.reason {
+			t.Errorf("%#v: got error %v, want reason %q", tc.value, err, tc.reason)
+		}
+	}
+}
+
+func TestReportAttributeError(t *testing.T) {
+	const bean = "Hadoop:service=NameNode,name=TestReportAttributeError"
+	bn := jmxBean{"name": bean, "Null": nil, "Bool": true, "Numeric": "7", "Text": "n/a", "List": []interface{}{}}
+	for _, tc := range []struct {
+		attribute string
+		reason    string
+	}{
+		{"Missing", reasonMissing},
+		{"Null", reasonNull},
+		{"Text", reasonInvalidValue},
+		{"List", reasonInvalidType},
+		{"Bool", ""},
+		{"Numeric", ""},
+	} {
+		_, err := bn.number(tc.attribute)
+		if tc.reason == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %s", tc.attribute, err)
+			}
+			continue
+		}
+		before := attributeErrorCount(bean, tc.attribute, tc.reason)
+		reportAttributeError(bean, tc.attribute, err)
+		if got := attributeErrorCount(bean, tc.attribute, tc.reason) - before; got != 1 {
+			t.Errorf("%s: counted %v errors with reason %q, want 1", tc.attribute, got, tc.reason)
+		}
+	}
+}
+
+func TestDecodeJSON(t *testing.T) {
+	const bean = "Hadoop:service=NameNode,name=TestDecodeJSON"
+	bn := jmxBean{"name": bean, "Valid": `{"a":1}`, "Empty": `{}`, "Malformed": `{"a":`, "Object": map[string]interface{}{}}
+	for _, tc := range []struct {
+		attribute string
+		ok        bool
+		reason    string
+	}{
+		{"Valid", true, ""},
+		{"Empty", true, ""},
+		{"Malformed", false, reasonInvalidValue},
+		{"Object", false, reasonInvalidType},
+		{"Missing", false, reasonMissing},
+	} {
+		before := attributeErrorCount(bean, tc.attribute, tc.reason)
+		var v map[string]float64
+		if ok := bn.decodeJSON(tc.attribute, &v); ok != tc.ok {
+			t.Errorf("%s: got %t, want %t", tc.attribute, ok, tc.ok)
+		}
+		if tc.reason != "" {
+			if got := attributeErrorCount(bean, tc.attribute, tc.reason) - before; got != 1 {
+				t.Errorf("%s: counted %v errors with reason %q, want 1", tc.attribute, got, tc.reason)
+			}
+		}
+	}
+}
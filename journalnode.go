@@ -0,0 +1,108 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// journalnodeRules are the mapping rules of the journalnode module used when
+// the rules file doesn't override them.
+const journalnodeRules = `
+rules:
+  - bean: 'java\.lang:type=Runtime'
+    attribute: Uptime
+    name: uptime_seconds
+    help: Number of seconds since the journalnode started.
+    type: gauge
+    scale: 0.001
+
+  # journal metrics, the bean of each journal is named Journal-<journal id>
+  - bean: 'Hadoop:service=JournalNode,name=Journal-(.+)'
+    attribute: LastWriterEpoch
+    name: journal_last_writer_epoch
+    help: Epoch of the last namenode which wrote to the journal.
+    type: gauge
+    labels: {journal: $1}
+  - bean: 'Hadoop:service=JournalNode,name=Journal-(.+)'
+    attribute: LastPromisedEpoch
+    name: journal_last_promised_epoch
+    help: Highest epoch promised to a namenode by the journal.
+    type: gauge
+    labels: {journal: $1}
+  - bean: 'Hadoop:service=JournalNode,name=Journal-(.+)'
+    attribute: LastWrittenTxId
+    name: journal_last_written_transaction_id
+    help: Highest transaction id written to the journal.
+    type: gauge
+    labels: {journal: $1}
+  - bean: 'Hadoop:service=JournalNode,name=Journal-(.+)'
+    attribute: CurrentLagTxns
+    name: journal_lag_transactions
+    help: Number of transactions the journal lags behind the other journalnodes of the quorum.
+    type: gauge
+    labels: {journal: $1}
+  - bean: 'Hadoop:service=JournalNode,name=Journal-(.+)'
+    attribute: BatchesWritten
+    name: journal_batches_written_total
+    help: Number of edit batches written to the journal.
+    type: counter
+    labels: {journal: $1}
+  - bean: 'Hadoop:service=JournalNode,name=Journal-(.+)'
+    attribute: BatchesWrittenWhileLagging
+    name: journal_batches_written_while_lagging_total
+    help: Number of edit batches written to the journal while it was lagging.
+    type: counter
+    labels: {journal: $1}
+  - bean: 'Hadoop:service=JournalNode,name=Journal-(.+)'
+    attribute: TxnsWritten
+    name: journal_transactions_written_total
+    help: Number of transactions written to the journal.
+    type: counter
+    labels: {journal: $1}
+  - bean: 'Hadoop:service=JournalNode,name=Journal-(.+)'
+    attribute: BytesWritten
+    name: journal_bytes_written_total
+    help: Number of bytes written to the journal.
+    type: counter
+    labels: {journal: $1}
+`
+
+const journalnodeInfoBean = "Hadoop:service=JournalNode,name=JournalNodeInfo"
+
+// journalsStatusCollector maps the JournalsStatus attribute of
+// JournalNodeInfo, a JSON document with the state of every journal keyed by
+// the journal id.
+var journalsStatusCollector = &beanCollector{
+	bean:       journalnodeInfoBean,
+	attributes: []string{"JournalsStatus"},
+	collect:    collectJournalsStatus,
+}
+
+func collectJournalsStatus(e *Exporter, bean jmxBean, ch chan<- prometheus.Metric) {
+	var journals map[string]map[string]interface{}
+	if !bean.decodeJSON("JournalsStatus", &journals) {
+		return
+	}
+
+	formatted := prometheus.NewDesc(
+		prometheus.BuildFQName(e.module.name, "journal", "formatted"),
+		"Whether the journal is formatted (1) or not (0).",
+		[]string{"journal"},
+		e.constLabels,
+	)
+
+	ids := make([]string, 0, len(journals))
+	for id := range journals {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		value, err := decodeNumber(journals[id]["Formatted"])
+		if err != nil {
+			reportAttributeError(bean.name(), "JournalsStatus", err)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(formatted, prometheus.GaugeValue, value, id)
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	garbageCollectorBeanPrefix = "java.lang:type=GarbageCollector,name="
+	memoryBean                 = "java.lang:type=Memory"
+	memoryPoolBeanPrefix       = "java.lang:type=MemoryPool,name="
+)
+
+// jvmCollectors are the bean collectors of the platform beans of the JVM
+// shared by all modules.
+var jvmCollectors = []*beanCollector{
+	gcInfoCollector,
+	memoryCollector,
+	memoryPoolCollector,
+}
+
+// gcInfoCollector maps the LastGcInfo attribute of the garbage collector
+// beans, which describes the last collection of each collector.
+var gcInfoCollector = &beanCollector{
+	bean:    garbageCollectorBeanPrefix,
+	prefix:  true,
+	collect: collectGCInfo,
+}
+
+// memoryUsage is a java.lang.management.MemoryUsage, in bytes. max is -1 if
+// undefined.
+type memoryUsage struct {
+	Init      float64 `json:"init"`
+	Used      float64 `json:"used"`
+	Committed float64 `json:"committed"`
+	Max       float64 `json:"max"`
+}
+
+// poolUsages is the usage of the memory pools, tabular data keyed by pool.
+type poolUsages []struct {
+	Key   string      `json:"key"`
+	Value memoryUsage `json:"value"`
+}
+
+// gcInfo is a com.sun.management.GcInfo, times are in milliseconds.
+type gcInfo struct {
+	Duration            float64    `json:"duration"`
+	MemoryUsageBeforeGc poolUsages `json:"memoryUsageBeforeGc"`
+	MemoryUsageAfterGc  poolUsages `json:"memoryUsageAfterGc"`
+}
+
+func collectGCInfo(e *Exporter, bean jmxBean, ch chan<- prometheus.Metric) {
+	if bean["LastGcInfo"] == nil {
+		// The collector didn't run yet, or the JVM doesn't report it.
+		return
+	}
+	var info gcInfo
+	if !bean.decode("LastGcInfo", &info) {
+		return
+	}
+
+	var (
+		collector = strings.TrimPrefix(bean.name(), garbageCollectorBeanPrefix)
+		duration  = prometheus.NewDesc(
+			prometheus.BuildFQName(e.module.name, "jvm_gc", "last_duration_seconds"),
+			"Duration of the last collection of the garbage collector.",
+			[]string{"collector"},
+			e.constLabels,
+		)
+		before = prometheus.NewDesc(
+			prometheus.BuildFQName(e.module.name, "jvm_gc", "last_memory_used_before_bytes"),
+			"Memory used in the pool before the last collection of the garbage collector.",
+			[]string{"collector", "pool"},
+			e.constLabels,
+		)
+		after = prometheus.NewDesc(
+			prometheus.BuildFQName(e.module.name, "jvm_gc", "last_memory_used_after_bytes"),
+			"Memory used in the pool after the last collection of the garbage collector.",
+			[]string{"collector", "pool"},
+			e.constLabels,
+		)
+	)
+	ch <- prometheus.MustNewConstMetric(duration, prometheus.GaugeValue, info.Duration/1000, collector)
+	for _, u := range []struct {
+		desc   *prometheus.Desc
+		usages poolUsages
+	}{
+		{before, info.MemoryUsageBeforeGc},
+		{after, info.MemoryUsageAfterGc},
+	} {
+		sort.Slice(u.usages, func(i, j int) bool { return u.usages[i].Key < u.usages[j].Key })
+		for _, pool := range u.usages {
+			ch <- prometheus.MustNewConstMetric(u.desc, prometheus.GaugeValue, pool.Value.Used, collector, pool.Key)
+		}
+	}
+}
+
+// memoryCollector maps the heap and non-heap usage of the Memory bean, in
+// bytes unlike the rounded megabytes of JvmMetrics.
+var memoryCollector = &beanCollector{
+	bean:       memoryBean,
+	attributes: []string{"HeapMemoryUsage", "NonHeapMemoryUsage"},
+	collect:    collectMemory,
+}
+
+func collectMemory(e *Exporter, bean jmxBean, ch chan<- prometheus.Metric) {
+	descs := newMemoryUsageDescs(e, "memory", "the memory area", "area")
+	for _, a := range []struct {
+		attribute string
+		area      string
+	}{
+		{"HeapMemoryUsage", "heap"},
+		{"NonHeapMemoryUsage", "nonheap"},
+	} {
+		var usage memoryUsage
+		if bean.decode(a.attribute, &usage) {
+			descs.collect(ch, usage, a.area)
+		}
+	}
+}
+
+// memoryPoolCollector maps the usage of every memory pool, such as the old
+// generation of the heap, from the MemoryPool beans.
+var memoryPoolCollector = &beanCollector{
+	bean:    memoryPoolBeanPrefix,
+	prefix:  true,
+	collect: collectMemoryPool,
+}
+
+func collectMemoryPool(e *Exporter, bean jmxBean, ch chan<- prometheus.Metric) {
+	pool := strings.TrimPrefix(bean.name(), memoryPoolBeanPrefix)
+	var usage memoryUsage
+	if bean.decode("Usage", &usage) {
+		newMemoryUsageDescs(e, "memory_pool", "the memory pool", "pool").collect(ch, usage, pool)
+	}
+
+	var (
+		peak = prometheus.NewDesc(
+			prometheus.BuildFQName(e.module.name, "jvm_memory_pool", "peak_used_bytes"),
+			"Peak memory used in the memory pool since the JVM started.",
+			[]string{"pool"},
+			e.constLabels,
+		)
+		collection = prometheus.NewDesc(
+			prometheus.BuildFQName(e.module.name, "jvm_memory_pool", "collection_used_bytes"),
+			"Memory used in the memory pool after the last collection of the pool.",
+			[]string{"pool"},
+			e.constLabels,
+		)
+	)
+	if bean.decode("PeakUsage", &usage) {
+		ch <- prometheus.MustNewConstMetric(peak, prometheus.GaugeValue, usage.Used, pool)
+	}
+	// Only pools of the heap are collected.
+	if bean["CollectionUsage"] != nil && bean.decode("CollectionUsage", &usage) {
+		ch <- prometheus.MustNewConstMetric(collection, prometheus.GaugeValue, usage.Used, pool)
+	}
+}
+
+// memoryUsageDescs are the metrics of a memoryUsage.
+type memoryUsageDescs struct {
+	used, committed, max, init *prometheus.Desc
+}
+
+func newMemoryUsageDescs(e *Exporter, subsystem, of, label string) *memoryUsageDescs {
+	newDesc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(
+			prometheus.BuildFQName(e.module.name, "jvm_"+subsystem, name),
+			help+" "+of+".",
+			[]string{label},
+			e.constLabels,
+		)
+	}
+	return &memoryUsageDescs{
+		used:      newDesc("used_bytes", "Memory used in"),
+		committed: newDesc("committed_bytes", "Memory committed by the JVM for"),
+		max:       newDesc("max_bytes", "Maximum memory usable in"),
+		init:      newDesc("init_bytes", "Memory initially requested by the JVM for"),
+	}
+}
+
+// collect delivers the usage, without a maximum if it is undefined.
+func (d *memoryUsageDescs) collect(ch chan<- prometheus.Metric, usage memoryUsage, labelValue string) {
+	ch <- prometheus.MustNewConstMetric(d.used, prometheus.GaugeValue, usage.Used, labelValue)
+	ch <- prometheus.MustNewConstMetric(d.committed, prometheus.GaugeValue, usage.Committed, labelValue)
+	if usage.Max >= 0 {
+		ch <- prometheus.MustNewConstMetric(d.max, prometheus.GaugeValue, usage.Max, labelValue)
+	}
+	ch <- prometheus.MustNewConstMetric(d.init, prometheus.GaugeValue, usage.Init, labelValue)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/jcmturner/gokrb5/v8/client"
+	"github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/spnego"
+	"github.com/prometheus/common/log"
+)
+
+// authError is returned when the SPNEGO negotiation with a namenode fails.
+type authError struct {
+	err error
+}
+
+func (e *authError) Error() string {
+	return fmt.Sprintf("kerberos authentication failed: %s", e.err)
+}
+
+// spnegoTransport authenticates requests with SPNEGO when the server
+// answers with a "WWW-Authenticate: Negotiate" challenge, as the web UIs of
+// kerberized Hadoop clusters do.
+type spnegoTransport struct {
+	base http.RoundTripper
+	// setHeader sets the Negotiate authorization header of a request.
+	setHeader func(req *http.Request) error
+}
+
+// newSPNEGOTransport logs in with the given keytab and principal, e.g.
+// "prometheus/host.example.com@EXAMPLE.COM". The ticket granting ticket is
+// renewed automatically, or requested again once it can't be renewed.
+// If spn is empty, the SPN "HTTP/<host>" of each request is used.
+func newSPNEGOTransport(base http.RoundTripper, krb5Config, keytabFile, principal, spn string) (*spnegoTransport, error) {
+	cfg, err := config.Load(krb5Config)
+	if err != nil {
+		return nil, fmt.Errorf("can't load kerberos configuration %q: %s", krb5Config, err)
+	}
+	kt, err := keytab.Load(keytabFile)
+	if err != nil {
+		return nil, fmt.Errorf("can't load keytab %q: %s", keytabFile, err)
+	}
+
+	at := strings.LastIndex(principal, "@")
+	if at < 0 {
+		if cfg.LibDefaults.DefaultRealm == "" {
+			return nil, fmt.Errorf("kerberos principal %q has no realm and no default realm is configured", principal)
+		}
+		principal += "@" + cfg.LibDefaults.DefaultRealm
+		at = strings.LastIndex(principal, "@")
+	}
+	username, realm := principal[:at], principal[at+1:]
+	if username == "" || realm == "" {
+		return nil, fmt.Errorf("invalid kerberos principal %q", principal)
+	}
+
+	cl := client.NewWithKeytab(username, realm, kt, cfg, client.DisablePAFXFAST(true))
+	if err := cl.Login(); err != nil {
+		// Not fatal, the login is retried with the next request.
+		log.Errorf("Kerberos login as %s failed: %s", principal, err)
+	}
+
+	return &spnegoTransport{
+		base: base,
+		setHeader: func(req *http.Request) error {
+			return spnego.SetSPNEGOHeader(cl, req, spn)
+		},
+	}, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *spnegoTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || !strings.HasPrefix(resp.Header.Get("WWW-Authenticate"), "Negotiate") {
+		return resp, err
+	}
+	ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	// A RoundTripper must not modify the request.
+	authReq := new(http.Request)
+	*authReq = *req
+	authReq.Header = make(http.Header, len(req.Header))
+	for k, v := range req.Header {
+		authReq.Header[k] = v
+	}
+	if err := t.setHeader(authReq); err != nil {
+		return nil, &authError{err: err}
+	}
+	return t.base.RoundTrip(authReq)
+}
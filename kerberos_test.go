@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const testNegotiateToken = "Negotiate dGVzdA=="
+
+// newNegotiateServer returns a stand-in for a kerberized web UI, which
+// challenges requests without the expected Negotiate header.
+func newNegotiateServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != testNegotiateToken {
+			w.Header().Set("WWW-Authenticate", "Negotiate")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"beans":[]}`))
+	}))
+}
+
+func newTestSPNEGOClient(setHeader func(req *http.Request) error) *http.Client {
+	return &http.Client{Transport: &spnegoTransport{base: http.DefaultTransport, setHeader: setHeader}}
+}
+
+func TestSPNEGOTransportNegotiates(t *testing.T) {
+	server := newNegotiateServer()
+	defer server.Close()
+
+	negotiations := 0
+	c := newTestSPNEGOClient(func(req *http.Request) error {
+		negotiations++
+		req.Header.Set("Authorization", testNegotiateToken)
+		return nil
+	})
+	resp, err := c.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("got status %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if string(body) != `{"beans":[]}` {
+		t.Errorf("got body %q", body)
+	}
+	if negotiations != 1 {
+		t.Errorf("got %d negotiations, want 1", negotiations)
+	}
+}
+
+func TestSPNEGOTransportPassesUnauthenticatedResponses(t *testing.T) {
+	for _, h := range []http.HandlerFunc{
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"beans":[]}`))
+		},
+		// Challenges for other schemes are left to the caller.
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("WWW-Authenticate", "Basic")
+			w.WriteHeader(http.StatusUnauthorized)
+		},
+	} {
+		server := httptest.NewServer(h)
+		want, err := http.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want.Body.Close()
+
+		c := newTestSPNEGOClient(func(req *http.Request) error {
+			t.Error("unexpected negotiation")
+			return nil
+		})
+		resp, err := c.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		server.Close()
+
+		if resp.StatusCode != want.StatusCode {
+			t.Errorf("got status %d, want %d", resp.StatusCode, want.StatusCode)
+		}
+		if got, want := resp.Header.Get("WWW-Authenticate"), want.Header.Get("WWW-Authenticate"); got != want {
+			t.Errorf("got WWW-Authenticate %q, want %q", got, want)
+		}
+	}
+}
+
+func TestSPNEGOTransportFailures(t *testing.T) {
+	server := newNegotiateServer()
+	defer server.Close()
+
+	for name, setHeader := range map[string]func(req *http.Request) error{
+		"no ticket": func(req *http.Request) error {
+			return errors.New("no TGT")
+		},
+		"rejected token": func(req *http.Request) error {
+			req.Header.Set("Authorization", "Negotiate b3RoZXI=")
+			return nil
+		},
+	} {
+		e := &Exporter{url: server.URL, module: modules["namenode"], httpClient: newTestSPNEGOClient(setHeader)}
+		_, err := e.fetchJMX(server.URL)
+		if err == nil {
+			t.Errorf("%s: got no error", name)
+			continue
+		}
+		if reason := fetchErrorReason(err); reason != reasonAuthentication {
+			t.Errorf("%s: got reason %q for %q, want %q", name, reason, err, reasonAuthentication)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// module is a kind of Hadoop daemon the exporter can scrape. All daemons
+// serve their beans from the same JMX servlet, they only differ in the
+// beans and how these are mapped.
+type module struct {
+	// name selects the module and prefixes its metrics.
+	name string
+	// service is the service property of the daemon's Hadoop beans.
+	service string
+	// rules are the built-in mapping rules, the JVM rules are appended.
+	rules string
+	// collectors are the built-in bean collectors, the JVM collectors are
+	// appended.
+	collectors []*beanCollector
+}
+
+var modules = map[string]*module{
+	"namenode": {
+		name:    "namenode",
+		service: "NameNode",
+		rules:   namenodeRules,
+		collectors: []*beanCollector{
+			liveNodesCollector,
+			deadNodesCollector,
+			storageCollector,
+			buildInfoCollector,
+			checkpointAgeCollector,
+			journalTransactionsCollector,
+			haStatusCollector,
+			rpcPortsCollector,
+			rpcMethodsCollector,
+			topUserOpsCollector,
+		},
+	},
+	"datanode": {
+		name:       "datanode",
+		service:    "DataNode",
+		rules:      datanodeRules,
+		collectors: []*beanCollector{volumeInfoCollector},
+	},
+	"journalnode": {
+		name:       "journalnode",
+		service:    "JournalNode",
+		rules:      journalnodeRules,
+		collectors: []*beanCollector{journalsStatusCollector},
+	},
+}
+
+// defaultRules returns the built-in mapping rules of the module.
+func (m *module) defaultRules() string {
+	return m.rules + strings.Replace(jvmRules, "{service}", m.service, -1)
+}
+
+// defaultCollectors returns the built-in bean collectors of the module.
+func (m *module) defaultCollectors() []*beanCollector {
+	collectors := make([]*beanCollector, 0, len(m.collectors)+len(jvmCollectors))
+	return append(append(collectors, m.collectors...), jvmCollectors...)
+}
+
+// beanCollector maps bean attributes which the rules can't express, such as
+// JSON documents embedded in string attributes.
+type beanCollector struct {
+	bean string
+	// prefix makes the collector handle every bean whose name starts with
+	// bean, such as the beans of the RPC servers named after their port.
+	prefix     bool
+	attributes []string
+	collect    func(e *Exporter, bean jmxBean, ch chan<- prometheus.Metric)
+	// enabled reports whether the collector runs with the given options,
+	// it always runs if nil.
+	enabled func(opts exporterOpts) bool
+}
+
+// handles reports whether the collector maps the bean of the given name.
+func (c *beanCollector) handles(beanName string) bool {
+	if c.prefix {
+		return strings.HasPrefix(beanName, c.bean)
+	}
+	return beanName == c.bean
+}
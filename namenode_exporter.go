@@ -1,14 +1,18 @@
 package main
 
 import (
-	"encoding/json"
 	"flag"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/cookiejar"
+	"net/url"
 	"os"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -18,462 +22,226 @@ import (
 
 var (
 	namenodeJmxURL     = flag.String("namenode.jmx.url", "http://localhost:50070/jmx", "Namenode JMX URL.")
+	moduleName         = flag.String("hadoop.module", "namenode", "Kind of Hadoop daemon served by namenode.jmx.url and probed by default, namenode, datanode or journalnode.")
+	hadoopConfDir      = flag.String("hadoop.conf-dir", "", "Optional Hadoop configuration directory, if set all namenodes found in core-site.xml and hdfs-site.xml are scraped instead of namenode.jmx.url.")
 	namenodeJmxTimeout = flag.Duration("namenode.jmx.timeout", 5*time.Second, "Timeout reading from namenode JMX URL.")
+	jmxConcurrency     = flag.Int("namenode.jmx.concurrency", 4, "Maximum number of parallel JMX queries per namenode.")
+	jmxPollInterval    = flag.Duration("namenode.jmx.poll-interval", 0, "Optional interval to poll the namenode JMX URL in the background, scrapes are then served from the last snapshot.")
+	jmxMaxStaleness    = flag.Duration("namenode.jmx.max-staleness", time.Minute, "Maximum age of a polled snapshot before the namenode is reported as down.")
+	jmxGetAttributes   = flag.Bool("namenode.jmx.get-attributes", false, "Fetch attributes named by the rules one by one with the get parameter instead of whole beans.")
+	rpcMethods         = flag.String("namenode.rpc-methods", ".*", "Regular expression of the RPC methods to export per-method metrics for, e.g. getBlockLocations|getListing|create.")
+	collectDatanodes   = flag.Bool("namenode.collect-datanodes", false, "Export per-datanode metrics parsed from the live nodes of the NameNodeInfo bean, which is large on big clusters.")
+	kerberosKeytab     = flag.String("namenode.kerberos.keytab", "", "Optional keytab file to authenticate against the namenode web UI with Kerberos SPNEGO.")
+	kerberosPrincipal  = flag.String("namenode.kerberos.principal", "", "Kerberos principal to log in as with the keytab, e.g. prometheus/host.example.com@EXAMPLE.COM.")
+	kerberosConfig     = flag.String("namenode.kerberos.config", "/etc/krb5.conf", "Kerberos configuration file.")
+	kerberosSPN        = flag.String("namenode.kerberos.spn", "", "Optional service principal name of the namenode web UI, HTTP/<host> of the JMX URL if empty.")
+	tlsCAFile          = flag.String("namenode.tls.ca-file", "", "Optional CA bundle to verify the certificate of HTTPS namenode JMX URLs, the system roots are used if empty.")
+	tlsCertFile        = flag.String("namenode.tls.cert-file", "", "Optional client certificate file to present to the namenode.")
+	tlsKeyFile         = flag.String("namenode.tls.key-file", "", "Optional client key file to present to the namenode.")
+	tlsServerName      = flag.String("namenode.tls.server-name", "", "Optional server name to verify the namenode certificate against, the host of the JMX URL if empty.")
+	tlsMinVersion      = flag.String("namenode.tls.min-version", "", "Optional minimum TLS version to accept, one of TLS10, TLS11, TLS12 or TLS13.")
 	pidFile            = flag.String("namenode.pid-file", "", "Optional path to a file containing the namenode PID for additional metrics.")
 	showVersion        = flag.Bool("version", false, "Print version information.")
 	listenAddress      = flag.String("web.listen-address", ":9779", "Address to listen on for web interface and telemetry.")
+	rulesFile          = flag.String("config.rules-file", "", "Optional YAML file with rules mapping JMX bean attributes to metrics, the built-in rules are used if empty.")
 	metricsPath        = flag.String("web.telemetry-path", "/metrics", "Path under which to expose metrics.")
+	probePath          = flag.String("web.probe-path", "/probe", "Path under which to expose metrics of the namenode given by the target parameter.")
 )
 
 const (
 	namespace = "namenode"
 )
 
-// Exporter collects metrics from a namenode server.
+// exporterOpts holds the settings shared by all exporters of this process.
+type exporterOpts struct {
+	httpClient       *http.Client
+	rules            map[string][]*rule
+	concurrency      int
+	getAttributes    bool
+	collectDatanodes bool
+	rpcMethods       *regexp.Regexp
+}
+
+// Exporter collects metrics from a Hadoop daemon, a namenode unless another
+// module is given.
 type Exporter struct {
-	url        string
-	httpClient *http.Client
-
-	// namenode server health metrics
-	up            *prometheus.Desc // DONE!!! gauge -> validated by connecting the the JMX endpoint
-	uptime        *prometheus.Desc // DONE!!! gauge -> "java.lang:type=Runtime" -> Uptime
-	state         *prometheus.Desc // DONE!!! gauge -> "Hadoop:service=NameNode,name=NameNodeStatus" -> State -> string
-	fsOperational *prometheus.Desc // DONE!!! gauge -> "Hadoop:service=NameNode,name=FSNamesystemState" -> FSState -> string
-	safemodeOn    *prometheus.Desc // DONE!!! gauge -> "Hadoop:service=NameNode,name=NameNodeInfo" -> Safemode
-	dataNodesLive *prometheus.Desc // DONE!!! gauge -> "Hadoop:service=NameNode,name=FSNamesystemState" -> NumLiveDataNodes -> int
-	dataNodesDead *prometheus.Desc // DONE!!! gauge -> "Hadoop:service=NameNode,name=FSNamesystemState" -> NumDeadDataNodes -> int
-
-	// dfs capacity metrics
-	dfsFilesTotal             *prometheus.Desc // DONE!!! gauge -> "Hadoop:service=NameNode,name=FSNamesystemState" -> FilesTotal
-	dfsPercentUsed            *prometheus.Desc // DONE!!! gauge -> "Hadoop:service=NameNode,name=NameNodeInfo" -> PercentUsed
-	dfsPercentRemaining       *prometheus.Desc // DONE!!! gauge -> "Hadoop:service=NameNode,name=NameNodeInfo" -> PercentRemaining
-	dfsCapacityBytesTotal     *prometheus.Desc // DONE!!! gauge -> "Hadoop:service=NameNode,name=FSNamesystemState" -> CapacityTotal -> int
-	dfsCapacityBytesUsed      *prometheus.Desc // DONE!!! gauge -> "Hadoop:service=NameNode,name=FSNamesystemState" -> CapacityUsed -> int
-	dfsCapacityBytesRemaining *prometheus.Desc // DONE!!! gauge -> "Hadoop:service=NameNode,name=FSNamesystemState" -> CapacityRemaining -> int
-	dfsNonDfsBytesUsed        *prometheus.Desc // DONE!!! gauge -> "Hadoop:service=NameNode,name=NameNodeInfo" -> NonDfsUsedSpace -> int
-
-	// dfs block metrics
-	dfsBlocksTotal                  *prometheus.Desc // gauge -> "Hadoop:service=NameNode,name=FSNamesystem" -> BlocksTotal
-	dfsBlocksUnderReplicated        *prometheus.Desc // gauge -> "Hadoop:service=NameNode,name=FSNamesystem" -> UnderReplicatedBlocks
-	dfsBlocksPendingReplication     *prometheus.Desc // gauge -> "Hadoop:service=NameNode,name=FSNamesystem" -> PendingReplicationBlocks
-	dfsBlocksScheduledReplication   *prometheus.Desc // gauge -> "Hadoop:service=NameNode,name=FSNamesystem" -> ScheduledReplicationBlocks
-	dfsBlocksPostponedMisreplicated *prometheus.Desc // gauge -> "Hadoop:service=NameNode,name=FSNamesystem" -> PostponedMisreplicatedBlocks
-	dfsBlocksPendingDeletion        *prometheus.Desc // gauge -> "Hadoop:service=NameNode,name=FSNamesystem" -> PendingDeletionBlocks
-	dfsBlocksMissing                *prometheus.Desc // gauge -> "Hadoop:service=NameNode,name=FSNamesystem" -> MissingBlocks
-	dfsBlocksCorrupt                *prometheus.Desc // gauge -> "Hadoop:service=NameNode,name=FSNamesystem" -> CorruptBlocks
-	dfsBlocksExcess                 *prometheus.Desc // gauge -> "Hadoop:service=NameNode,name=FSNamesystem" -> ExcessBlocks
-	dfsBlockPoolBytesUsed           *prometheus.Desc // DONE!!! gauge -> "Hadoop:service=NameNode,name=NameNodeInfo" -> BlockPoolUsedSpace
-	dfsBlockPoolPercentUsed         *prometheus.Desc // DONE!!! gauge -> "Hadoop:service=NameNode,name=NameNodeInfo" -> PercentBlockPoolUsed
-
-	// namenode jvm metrics
-	jvmLogFatal                     *prometheus.Desc // DONE!!! counter -> "Hadoop:service=NameNode,name=JvmMetrics" -> LogFatal
-	jvmLogError                     *prometheus.Desc // DONE!!! counter -> "Hadoop:service=NameNode,name=JvmMetrics" -> LogError
-	jvmLogWarn                      *prometheus.Desc // DONE!!! counter -> "Hadoop:service=NameNode,name=JvmMetrics" -> LogWarn
-	jvmLogInfo                      *prometheus.Desc // DONE!!! counter -> "Hadoop:service=NameNode,name=JvmMetrics" -> LogInfo
-	jvmMemHeapMegabytesUsed         *prometheus.Desc // DONE!!! gauge -> "Hadoop:service=NameNode,name=JvmMetrics" -> MemHeapUsedM
-	jvmMemHeapMegabytesCommitted    *prometheus.Desc // DONE!!! gauge -> "Hadoop:service=NameNode,name=JvmMetrics" -> MemHeapCommittedM
-	jvmMemNonHeapMegabytesUsed      *prometheus.Desc // DONE!!! gauge -> "Hadoop:service=NameNode,name=JvmMetrics" -> MemNonHeapUsedM
-	jvmMemNonHeapMegabytesCommitted *prometheus.Desc // DONE!!! gauge -> "Hadoop:service=NameNode,name=JvmMetrics" -> MemNonHeapCommittedM
-	jvmThreadsNew                   *prometheus.Desc // DONE!!! gauge -> "Hadoop:service=NameNode,name=JvmMetrics" -> ThreadsNew
-	jvmThreadsRunnable              *prometheus.Desc // DONE!!! gauge -> "Hadoop:service=NameNode,name=JvmMetrics" -> ThreadsRunnable
-	jvmThreadsBlocked               *prometheus.Desc // DONE!!! gauge -> "Hadoop:service=NameNode,name=JvmMetrics" -> ThreadsBlocked
-	jvmThreadsWaiting               *prometheus.Desc // DONE!!! gauge -> "Hadoop:service=NameNode,name=JvmMetrics" -> ThreadsWaiting
-	jvmThreadsTimedWaiting          *prometheus.Desc // DONE!!! gauge -> "Hadoop:service=NameNode,name=JvmMetrics" -> ThreadsTimedWaiting
-	jvmThreadsTerminated            *prometheus.Desc // DONE!!! gauge -> "Hadoop:service=NameNode,name=JvmMetrics" -> ThreadsTerminated
+	url         string
+	module      *module
+	httpClient  *http.Client
+	rules       []*rule
+	collectors  []*beanCollector
+	constLabels prometheus.Labels
+	// rpcPorts maps RPC ports known from the Hadoop configuration to their
+	// role, see rpcPortsCollector.
+	rpcPorts   map[string]string
+	rpcMethods *regexp.Regexp
+
+	queries     []jmxQuery
+	concurrency int
+	target      *targetState
+
+	mu           sync.RWMutex
+	polling      bool
+	maxStaleness time.Duration
+	snapshot     *jmxEnvelope
+	snapshotTime time.Time
+	certNotAfter time.Time
+
+	up          *prometheus.Desc
+	snapshotAge *prometheus.Desc
+	certExpiry  *prometheus.Desc
 }
 
-// NewExporter returns an initialized exporter.
-func NewExporter(url string, timeout time.Duration) *Exporter {
+// NewExporter returns an initialized exporter for a daemon of the given
+// module. The given constant labels are attached to every metric, which allows
+// several exporters to share a registry.
+func NewExporter(url string, m *module, opts exporterOpts, constLabels prometheus.Labels) *Exporter {
+	rules := opts.rules[m.name]
+	var collectors []*beanCollector
+	for _, c := range m.defaultCollectors() {
+		if c.enabled == nil || c.enabled(opts) {
+			collectors = append(collectors, c)
+		}
+	}
 	return &Exporter{
-		url:        url,
-		httpClient: &http.Client{Timeout: timeout},
+		url:         url,
+		module:      m,
+		httpClient:  opts.httpClient,
+		rules:       rules,
+		collectors:  collectors,
+		constLabels: constLabels,
+		rpcMethods:  opts.rpcMethods,
+
+		queries:     jmxQueries(rules, collectors, opts.getAttributes),
+		concurrency: opts.concurrency,
+		target:      &targetState{},
 
-		// namenode server health metrics
 		up: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "", "up"),
-			"Could the namenode be reached.",
-			nil,
-			nil,
-		),
-		uptime: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "", "uptime_seconds"),
-			"Number of seconds since the namenode started.",
-			nil,
-			nil,
-		),
-		state: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "", "state"),
-			"Indicate namenode state (0 - standby, 1 - active).",
-			nil,
-			nil,
-		),
-		fsOperational: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "", "fs_operational"),
-			"The filesystem state of this namenode.",
-			nil,
-			nil,
-		),
-		safemodeOn: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "", "safemode_on"),
-			"The safemode state of this namenode.",
-			nil,
-			nil,
-		),
-		dataNodesLive: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "", "data_nodes_live"),
-			"The number of live datanodes in this DFS.",
-			nil,
-			nil,
-		),
-		dataNodesDead: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "", "data_nodes_dead"),
-			"The number of dead datanodes in this DFS.",
-			nil,
-			nil,
-		),
-
-		// dfs capacity metrics
-		dfsFilesTotal: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "dfs", "files_total"),
-			"Total number of files in DFS.",
-			nil,
-			nil,
-		),
-		dfsPercentUsed: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "dfs", "percent_used"),
-			"TODO(fahlke): describe this metric",
-			nil,
-			nil,
-		),
-		dfsPercentRemaining: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "dfs", "percent_remaining"),
-			"TODO(fahlke): describe this metric",
-			nil,
-			nil,
-		),
-		dfsCapacityBytesTotal: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "dfs", "capacity_bytes_total"),
-			"Total configured DFS storage capacity in bytes.",
-			nil,
-			nil,
-		),
-		dfsCapacityBytesUsed: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "dfs", "capacity_bytes_used"),
-			"The usage of the DFS in bytes.",
-			nil,
-			nil,
-		),
-		dfsCapacityBytesRemaining: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "dfs", "capacity_bytes_remaining"),
-			"The remaining capacity of the DFS in bytes.",
-			nil,
-			nil,
-		),
-		dfsNonDfsBytesUsed: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "dfs", "non_dfs_bytes_used"),
-			"Non-DFS usage in bytes.",
-			nil,
-			nil,
-		),
-
-		// dfs block metrics
-		dfsBlocksTotal: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "dfs", "blocks_total"),
-			"Total blocks in DFS.",
-			nil,
-			nil,
-		),
-		dfsBlocksUnderReplicated: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "dfs", "blocks_under_replicated"),
-			"Under replicated blocks in DFS.",
-			nil,
-			nil,
-		),
-		dfsBlocksPendingReplication: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "dfs", "blocks_pending_replication"),
-			"TODO(fahlke): describe this metric",
-			nil,
-			nil,
-		),
-		dfsBlocksScheduledReplication: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "dfs", "blocks_scheduled_replication"),
-			"TODO(fahlke): describe this metric",
-			nil,
-			nil,
-		),
-		dfsBlocksPostponedMisreplicated: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "dfs", "blocks_postponed_misreplicated"),
-			"TODO(fahlke): describe this metric",
-			nil,
-			nil,
-		),
-		dfsBlocksPendingDeletion: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "dfs", "blocks_pending_deletion"),
-			"TODO(fahlke): describe this metric",
-			nil,
-			nil,
-		),
-		dfsBlocksMissing: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "dfs", "blocks_missing"),
-			"Missing blocks in DFS.",
-			nil,
-			nil,
-		),
-		dfsBlocksCorrupt: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "dfs", "blocks_corrupt"),
-			"Corrupted blocks in DFS.",
-			nil,
-			nil,
-		),
-		dfsBlocksExcess: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "dfs", "blocks_excess"),
-			"Excess blocks in DFS.",
-			nil,
-			nil,
-		),
-		dfsBlockPoolBytesUsed: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "dfs", "block_pool_bytes_used"),
-			"TODO(fahlke): describe this metric",
-			nil,
-			nil,
-		),
-		dfsBlockPoolPercentUsed: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "dfs", "block_pool_percent_used"),
-			"TODO(fahlke): describe this metric",
-			nil,
-			nil,
-		),
-
-		// namenode jvm metrics
-		jvmLogFatal: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "jvm", "log_fatal"),
-			"TODO(fahlke): describe this metric",
-			nil,
-			nil,
-		),
-		jvmLogError: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "jvm", "log_error"),
-			"TODO(fahlke): describe this metric",
-			nil,
-			nil,
-		),
-		jvmLogWarn: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "jvm", "log_warn"),
-			"TODO(fahlke): describe this metric",
-			nil,
-			nil,
-		),
-		jvmLogInfo: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "jvm", "log_info"),
-			"TODO(fahlke): describe this metric",
-			nil,
-			nil,
-		),
-		jvmMemHeapMegabytesUsed: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "jvm", "mem_heap_megabytes_used"),
-			"TODO(fahlke): describe this metric",
-			nil,
-			nil,
-		),
-		jvmMemHeapMegabytesCommitted: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "jvm", "mem_heap_megabytes_committed"),
-			"TODO(fahlke): describe this metric",
-			nil,
-			nil,
-		),
-		jvmMemNonHeapMegabytesUsed: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "jvm", "mem_non_heap_megabytes_used"),
-			"TODO(fahlke): describe this metric",
-			nil,
-			nil,
-		),
-		jvmMemNonHeapMegabytesCommitted: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "jvm", "mem_non_heap_megabytes_committed"),
-			"TODO(fahlke): describe this metric",
-			nil,
-			nil,
-		),
-		jvmThreadsNew: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "jvm", "threads_new"),
-			"TODO(fahlke): describe this metric",
-			nil,
-			nil,
-		),
-		jvmThreadsRunnable: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "jvm", "threads_runnable"),
-			"TODO(fahlke): describe this metric",
-			nil,
-			nil,
-		),
-		jvmThreadsBlocked: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "jvm", "threads_blocked"),
-			"TODO(fahlke): describe this metric",
-			nil,
+			prometheus.BuildFQName(m.name, "", "up"),
+			fmt.Sprintf("Could the %s be reached.", m.name),
 			nil,
+			constLabels,
 		),
-		jvmThreadsWaiting: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "jvm", "threads_waiting"),
-			"TODO(fahlke): describe this metric",
-			nil,
+		snapshotAge: prometheus.NewDesc(
+			prometheus.BuildFQName(m.name, "exporter", "snapshot_age_seconds"),
+			"Age of the polled JMX snapshot the metrics are served from.",
 			nil,
+			constLabels,
 		),
-		jvmThreadsTimedWaiting: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "jvm", "threads_timed_waiting"),
-			"TODO(fahlke): describe this metric",
-			nil,
-			nil,
-		),
-		jvmThreadsTerminated: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "jvm", "threads_terminated"),
-			"TODO(fahlke): describe this metric",
-			nil,
+		certExpiry: prometheus.NewDesc(
+			prometheus.BuildFQName(m.name, "tls", "certificate_expiry_timestamp_seconds"),
+			fmt.Sprintf("Expiry of the certificate served by the %s JMX URL, in seconds since the epoch.", m.name),
 			nil,
+			constLabels,
 		),
 	}
 }
 
-// Describe describes all the metrics exported by the namenode exporter.
-// It implements prometheus.Collector.
+// Describe describes all the metrics exported by the exporter.
+// It implements prometheus.Collector. The metrics produced by the mapping
+// rules depend on the scraped beans, so only the static ones are described.
 func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
-	// namenode server health metrics
 	ch <- e.up
-	ch <- e.uptime
-	ch <- e.state
-	ch <- e.fsOperational
-	ch <- e.safemodeOn
-	ch <- e.dataNodesLive
-	ch <- e.dataNodesDead
-
-	// dfs capacity metrics
-	ch <- e.dfsFilesTotal
-	ch <- e.dfsPercentUsed
-	ch <- e.dfsPercentRemaining
-	ch <- e.dfsCapacityBytesTotal
-	ch <- e.dfsCapacityBytesUsed
-	ch <- e.dfsCapacityBytesRemaining
-	ch <- e.dfsNonDfsBytesUsed
-
-	// dfs block metrics
-	ch <- e.dfsBlocksTotal
-	ch <- e.dfsBlocksUnderReplicated
-	ch <- e.dfsBlocksPendingReplication
-	ch <- e.dfsBlocksScheduledReplication
-	ch <- e.dfsBlocksPostponedMisreplicated
-	ch <- e.dfsBlocksPendingDeletion
-	ch <- e.dfsBlocksMissing
-	ch <- e.dfsBlocksCorrupt
-	ch <- e.dfsBlocksExcess
-	ch <- e.dfsBlockPoolBytesUsed
-	ch <- e.dfsBlockPoolPercentUsed
-
-	// namenode jvm metrics
-	ch <- e.jvmLogFatal
-	ch <- e.jvmLogError
-	ch <- e.jvmLogWarn
-	ch <- e.jvmLogInfo
-	ch <- e.jvmMemHeapMegabytesUsed
-	ch <- e.jvmMemHeapMegabytesCommitted
-	ch <- e.jvmMemNonHeapMegabytesUsed
-	ch <- e.jvmMemNonHeapMegabytesCommitted
-	ch <- e.jvmThreadsNew
-	ch <- e.jvmThreadsRunnable
-	ch <- e.jvmThreadsBlocked
-	ch <- e.jvmThreadsWaiting
-	ch <- e.jvmThreadsTimedWaiting
-	ch <- e.jvmThreadsTerminated
-}
-
-type jmxEnvelope struct {
-	Beans []jmxBean `json:"beans"`
+	ch <- e.snapshotAge
+	ch <- e.certExpiry
 }
 
-type jmxBean map[string]interface{}
+// Collect fetches the statistics from the configured Hadoop daemon, and
+// delivers them as Prometheus metrics. It implements prometheus.Collector.
+func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
+	e.mu.RLock()
+	polling := e.polling
+	e.mu.RUnlock()
+
+	var beans []jmxBean
+	if polling {
+		var ok bool
+		if beans, ok = e.collectSnapshot(ch); !ok {
+			ch <- prometheus.MustNewConstMetric(e.up, prometheus.GaugeValue, 0)
+			return
+		}
+	} else {
+		var err error
+		if beans, err = e.fetch(); err != nil {
+			ch <- prometheus.MustNewConstMetric(e.up, prometheus.GaugeValue, 0)
+			e.reportFetchError(err)
+			return
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(e.up, prometheus.GaugeValue, 1)
 
-func mustNewConstBoolMetric(desc *prometheus.Desc, valueType prometheus.ValueType, value bool, labelValues ...string) prometheus.Metric {
-	var fval float64
-	if value {
-		fval = 1
+	e.mu.RLock()
+	certNotAfter := e.certNotAfter
+	e.mu.RUnlock()
+	if !certNotAfter.IsZero() {
+		ch <- prometheus.MustNewConstMetric(e.certExpiry, prometheus.GaugeValue, float64(certNotAfter.Unix()))
 	}
 
-	return prometheus.MustNewConstMetric(desc, valueType, fval, labelValues...)
+	seen := map[string]bool{}
+	for _, bean := range beans {
+		e.collectBean(bean, seen, ch)
+		e.collectPercentiles(bean, seen, ch)
+		for _, c := range e.collectors {
+			if c.handles(bean.name()) {
+				c.collect(e, bean, ch)
+			}
+		}
+	}
 }
 
-// Collect fetches the statistics from the configured Namenode server, and
-// delivers them as Prometheus metrics. It implements prometheus.Collector.
-func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
-	resp, err := e.httpClient.Get(e.url)
-	if err != nil {
-		ch <- prometheus.MustNewConstMetric(e.up, prometheus.GaugeValue, 0)
-		log.Errorf("Failed to collect metrics from namenode: %s", err)
-		return
+// collectBean maps every attribute of a bean with the first matching rule.
+// Metrics already delivered in this scrape are skipped, as the registry
+// rejects duplicates. Attributes which can't be converted only skip their own
+// metric; they are counted when a rule names them explicitly.
+func (e *Exporter) collectBean(bean jmxBean, seen map[string]bool, ch chan<- prometheus.Metric) {
+	beanName := bean.name()
+
+	var rules []*rule
+	for _, r := range e.rules {
+		if r.beanRegexp.MatchString(beanName) {
+			rules = append(rules, r)
+			if r.literalAttribute != "" {
+				if _, ok := bean[r.literalAttribute]; !ok {
+					reportAttributeError(beanName, r.literalAttribute, &attributeError{reason: reasonMissing})
+				}
+			}
+		}
 	}
-	defer func() {
-		ioutil.ReadAll(resp.Body) // Mindless drain body upon exit
-		resp.Body.Close()
-	}()
-
-	if resp.StatusCode != http.StatusOK {
-		ch <- prometheus.MustNewConstMetric(e.up, prometheus.GaugeValue, 0)
-		log.Errorf("Failed to collect metrics from namenode: HTTP status code %d", resp.StatusCode)
+	if len(rules) == 0 {
 		return
 	}
 
-	var envelope jmxEnvelope
-	dec := json.NewDecoder(resp.Body)
-	err = dec.Decode(&envelope)
-	if err != nil {
-		ch <- prometheus.MustNewConstMetric(e.up, prometheus.GaugeValue, 0)
-		log.Errorf("Failed to collect metrics from namenode: %s", err)
-		return
+	attributes := make([]string, 0, len(bean))
+	for attribute := range bean {
+		attributes = append(attributes, attribute)
 	}
-	ch <- prometheus.MustNewConstMetric(e.up, prometheus.GaugeValue, 1)
+	sort.Strings(attributes)
 
-	for _, nameDataMap := range envelope.Beans {
-		switch nameDataMap["name"] {
-		case "java.lang:type=Runtime":
-			ch <- prometheus.MustNewConstMetric(e.uptime, prometheus.GaugeValue, nameDataMap["Uptime"].(float64))
-		case "Hadoop:service=NameNode,name=NameNodeStatus":
-			ch <- mustNewConstBoolMetric(e.state, prometheus.GaugeValue, nameDataMap["State"] == "active")
-		case "Hadoop:service=NameNode,name=FSNamesystem":
-			ch <- prometheus.MustNewConstMetric(e.dfsBlocksTotal, prometheus.GaugeValue, nameDataMap["BlocksTotal"].(float64))
-			ch <- prometheus.MustNewConstMetric(e.dfsBlocksUnderReplicated, prometheus.GaugeValue, nameDataMap["UnderReplicatedBlocks"].(float64))
-			ch <- prometheus.MustNewConstMetric(e.dfsBlocksPendingReplication, prometheus.GaugeValue, nameDataMap["PendingReplicationBlocks"].(float64))
-			ch <- prometheus.MustNewConstMetric(e.dfsBlocksScheduledReplication, prometheus.GaugeValue, nameDataMap["ScheduledReplicationBlocks"].(float64))
-			ch <- prometheus.MustNewConstMetric(e.dfsBlocksPostponedMisreplicated, prometheus.GaugeValue, nameDataMap["PostponedMisreplicatedBlocks"].(float64))
-			ch <- prometheus.MustNewConstMetric(e.dfsBlocksPendingDeletion, prometheus.GaugeValue, nameDataMap["PendingDeletionBlocks"].(float64))
-			ch <- prometheus.MustNewConstMetric(e.dfsBlocksMissing, prometheus.GaugeValue, nameDataMap["MissingBlocks"].(float64))
-			ch <- prometheus.MustNewConstMetric(e.dfsBlocksCorrupt, prometheus.GaugeValue, nameDataMap["CorruptBlocks"].(float64))
-			ch <- prometheus.MustNewConstMetric(e.dfsBlocksExcess, prometheus.GaugeValue, nameDataMap["ExcessBlocks"].(float64))
-		case "Hadoop:service=NameNode,name=FSNamesystemState":
-			ch <- mustNewConstBoolMetric(e.fsOperational, prometheus.GaugeValue, nameDataMap["FSState"] == "Operational")
-			ch <- prometheus.MustNewConstMetric(e.dataNodesLive, prometheus.GaugeValue, nameDataMap["NumLiveDataNodes"].(float64))
-			ch <- prometheus.MustNewConstMetric(e.dataNodesDead, prometheus.GaugeValue, nameDataMap["NumDeadDataNodes"].(float64))
-			ch <- prometheus.MustNewConstMetric(e.dfsFilesTotal, prometheus.GaugeValue, nameDataMap["FilesTotal"].(float64))
-			ch <- prometheus.MustNewConstMetric(e.dfsCapacityBytesTotal, prometheus.GaugeValue, nameDataMap["CapacityTotal"].(float64))
-			ch <- prometheus.MustNewConstMetric(e.dfsCapacityBytesUsed, prometheus.GaugeValue, nameDataMap["CapacityUsed"].(float64))
-			ch <- prometheus.MustNewConstMetric(e.dfsCapacityBytesRemaining, prometheus.GaugeValue, nameDataMap["CapacityRemaining"].(float64))
-		case "Hadoop:service=NameNode,name=NameNodeInfo":
-			ch <- mustNewConstBoolMetric(e.safemodeOn, prometheus.GaugeValue, nameDataMap["Safemode"] != "")
-			ch <- prometheus.MustNewConstMetric(e.dfsPercentUsed, prometheus.GaugeValue, nameDataMap["PercentUsed"].(float64))
-			ch <- prometheus.MustNewConstMetric(e.dfsPercentRemaining, prometheus.GaugeValue, nameDataMap["PercentRemaining"].(float64))
-			ch <- prometheus.MustNewConstMetric(e.dfsNonDfsBytesUsed, prometheus.GaugeValue, nameDataMap["NonDfsUsedSpace"].(float64))
-			ch <- prometheus.MustNewConstMetric(e.dfsBlockPoolBytesUsed, prometheus.GaugeValue, nameDataMap["BlockPoolUsedSpace"].(float64))
-			ch <- prometheus.MustNewConstMetric(e.dfsBlockPoolPercentUsed, prometheus.GaugeValue, nameDataMap["PercentBlockPoolUsed"].(float64))
-		case "Hadoop:service=NameNode,name=JvmMetrics":
-			ch <- prometheus.MustNewConstMetric(e.jvmLogFatal, prometheus.CounterValue, nameDataMap["LogFatal"].(float64))
-			ch <- prometheus.MustNewConstMetric(e.jvmLogError, prometheus.CounterValue, nameDataMap["LogError"].(float64))
-			ch <- prometheus.MustNewConstMetric(e.jvmLogWarn, prometheus.CounterValue, nameDataMap["LogWarn"].(float64))
-			ch <- prometheus.MustNewConstMetric(e.jvmLogInfo, prometheus.CounterValue, nameDataMap["LogInfo"].(float64))
-			ch <- prometheus.MustNewConstMetric(e.jvmMemHeapMegabytesUsed, prometheus.GaugeValue, nameDataMap["MemHeapUsedM"].(float64))
-			ch <- prometheus.MustNewConstMetric(e.jvmMemHeapMegabytesCommitted, prometheus.GaugeValue, nameDataMap["MemHeapCommittedM"].(float64))
-			ch <- prometheus.MustNewConstMetric(e.jvmMemNonHeapMegabytesUsed, prometheus.GaugeValue, nameDataMap["MemNonHeapUsedM"].(float64))
-			ch <- prometheus.MustNewConstMetric(e.jvmMemNonHeapMegabytesCommitted, prometheus.GaugeValue, nameDataMap["MemNonHeapCommittedM"].(float64))
-			ch <- prometheus.MustNewConstMetric(e.jvmThreadsNew, prometheus.GaugeValue, nameDataMap["ThreadsNew"].(float64))
-			ch <- prometheus.MustNewConstMetric(e.jvmThreadsRunnable, prometheus.GaugeValue, nameDataMap["ThreadsRunnable"].(float64))
-			ch <- prometheus.MustNewConstMetric(e.jvmThreadsBlocked, prometheus.GaugeValue, nameDataMap["ThreadsBlocked"].(float64))
-			ch <- prometheus.MustNewConstMetric(e.jvmThreadsWaiting, prometheus.GaugeValue, nameDataMap["ThreadsWaiting"].(float64))
-			ch <- prometheus.MustNewConstMetric(e.jvmThreadsTimedWaiting, prometheus.GaugeValue, nameDataMap["ThreadsTimedWaiting"].(float64))
-			ch <- prometheus.MustNewConstMetric(e.jvmThreadsTerminated, prometheus.GaugeValue, nameDataMap["ThreadsTerminated"].(float64))
+	for _, attribute := range attributes {
+		for _, r := range rules {
+			match, ok := r.match(beanName, attribute)
+			if !ok {
+				continue
+			}
+			value, err := r.value(bean[attribute])
+			if err != nil {
+				if r.literalAttribute != "" {
+					reportAttributeError(beanName, attribute, err)
+				}
+				break
+			}
+			m, key, err := r.metric(e.module.name, e.constLabels, beanName, attribute, match, value)
+			if err != nil {
+				log.Errorf("Failed to map %s::%s: %s", beanName, attribute, err)
+				break
+			}
+			if seen[key] {
+				log.Debugf("Dropping duplicate metric for %s::%s", beanName, attribute)
+				break
+			}
+			seen[key] = true
+			ch <- m
+			break
 		}
 	}
 }
@@ -489,7 +257,110 @@ func main() {
 	log.Infoln("Starting namenode_exporter", version.Info())
 	log.Infoln("Build context", version.BuildContext())
 
-	prometheus.MustRegister(NewExporter(*namenodeJmxURL, *namenodeJmxTimeout))
+	prometheus.MustRegister(attributeErrors, fetchErrors)
+
+	if *jmxConcurrency < 1 {
+		log.Fatalf("Invalid JMX concurrency %d, must be at least 1", *jmxConcurrency)
+	}
+	tlsConfig, err := newTLSConfig(*tlsCAFile, *tlsCertFile, *tlsKeyFile, *tlsServerName, *tlsMinVersion)
+	if err != nil {
+		log.Fatal(err)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	httpClient := &http.Client{Timeout: *namenodeJmxTimeout, Transport: transport}
+	if *kerberosKeytab != "" {
+		if *kerberosPrincipal == "" {
+			log.Fatal("A kerberos principal is required with a keytab")
+		}
+		spnegoTransport, err := newSPNEGOTransport(transport, *kerberosConfig, *kerberosKeytab, *kerberosPrincipal, *kerberosSPN)
+		if err != nil {
+			log.Fatal(err)
+		}
+		httpClient.Transport = spnegoTransport
+		// Keeps the hadoop.auth cookie, so that not every request negotiates.
+		httpClient.Jar, _ = cookiejar.New(nil)
+	}
+
+	rpcMethodsRegexp, err := regexp.Compile("^(?:" + *rpcMethods + ")$")
+	if err != nil {
+		log.Fatalf("Invalid RPC method regular expression %q: %s", *rpcMethods, err)
+	}
+
+	opts := exporterOpts{
+		httpClient:       httpClient,
+		rules:            map[string][]*rule{},
+		concurrency:      *jmxConcurrency,
+		getAttributes:    *jmxGetAttributes,
+		collectDatanodes: *collectDatanodes,
+		rpcMethods:       rpcMethodsRegexp,
+	}
+	for name, m := range modules {
+		rules, err := parseModuleRules([]byte(m.defaultRules()))
+		if err != nil {
+			log.Fatalf("Can't load default rules of module %q: %s", name, err)
+		}
+		opts.rules[name] = rules
+	}
+	if *rulesFile != "" {
+		rules, err := loadRulesFile(*rulesFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for name, moduleRules := range rules {
+			opts.rules[name] = moduleRules
+		}
+	}
+
+	m, ok := modules[*moduleName]
+	if !ok {
+		log.Fatalf("Unknown module %q", *moduleName)
+	}
+
+	var exporters []*Exporter
+	if *hadoopConfDir != "" {
+		if m.name != "namenode" {
+			log.Fatalf("hadoop.conf-dir only discovers namenodes, not the %s module", m.name)
+		}
+		conf, err := loadHadoopConfiguration(*hadoopConfDir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		endpoints, err := conf.namenodeEndpoints()
+		if err != nil {
+			log.Fatal(err)
+		}
+		// Nameservices with several namenodes, whose transactions are compared.
+		var (
+			nameservices     = map[string][]*Exporter{}
+			nameserviceNames []string
+		)
+		for _, ep := range endpoints {
+			log.Infof("Scraping namenode %s (nameservice %q, namenode %q)", ep.url, ep.nameservice, ep.namenodeID)
+			e := NewExporter(ep.url, m, opts, prometheus.Labels{
+				"nameservice": ep.nameservice,
+				"namenode_id": ep.namenodeID,
+			})
+			e.rpcPorts = ep.rpcPorts
+			exporters = append(exporters, e)
+			nameservices[ep.nameservice] = append(nameservices[ep.nameservice], e)
+			if len(nameservices[ep.nameservice]) == 2 {
+				nameserviceNames = append(nameserviceNames, ep.nameservice)
+			}
+		}
+		for _, name := range nameserviceNames {
+			prometheus.MustRegister(newHAPairCollector(name, nameservices[name]))
+		}
+	} else {
+		exporters = append(exporters, NewExporter(*namenodeJmxURL, m, opts, nil))
+	}
+
+	for _, e := range exporters {
+		if *jmxPollInterval > 0 {
+			e.startPolling(*jmxPollInterval, *jmxMaxStaleness)
+		}
+		prometheus.MustRegister(e)
+	}
 
 	if *pidFile != "" {
 		procExporter := prometheus.NewProcessCollectorPIDFn(func() (int, error) {
@@ -502,7 +373,7 @@ func main() {
 				return 0, fmt.Errorf("can't parse pid file %q: %s", *pidFile, err)
 			}
 			return value, nil
-		}, namespace)
+		}, m.name)
 		prometheus.MustRegister(procExporter)
 	}
 
@@ -511,11 +382,13 @@ func main() {
 <body>
 <h1>Namenode Exporter</h1>
 <p><a href='` + *metricsPath + `'>Metrics</a></p>
+<p><a href='` + *probePath + `?module=` + m.name + `&target=` + url.QueryEscape(*namenodeJmxURL) + `'>Probe ` + *namenodeJmxURL + `</a></p>
 </body>
 </html>
 `)
 
 	http.Handle(*metricsPath, prometheus.Handler())
+	http.Handle(*probePath, probeHandler(opts, m))
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Write(landingPage)
 	})
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const nameNodeInfoBean = "Hadoop:service=NameNode,name=NameNodeInfo"
+
+// liveNodesCollector maps the LiveNodes attribute of NameNodeInfo, a JSON
+// document with the state of every live datanode keyed by its name. It is
+// opt-in, as the attribute grows with the cluster and so does the number of
+// series.
+var liveNodesCollector = &beanCollector{
+	bean:       nameNodeInfoBean,
+	attributes: []string{"LiveNodes"},
+	collect:    collectLiveNodes,
+	enabled:    func(opts exporterOpts) bool { return opts.collectDatanodes },
+}
+
+// nodeField maps a numeric field of the node documents to a gauge.
+type nodeField struct {
+	field string
+	name  string
+	help  string
+	scale float64
+}
+
+var liveNodeFields = []nodeField{
+	{"capacity", "capacity_bytes", "Configured capacity of the datanode in bytes.", 1},
+	{"used", "used_bytes", "Space used by DFS blocks on the datanode in bytes.", 1},
+	{"remaining", "remaining_bytes", "Remaining space for DFS blocks on the datanode in bytes.", 1},
+	{"nonDfsUsedSpace", "non_dfs_used_bytes", "Space used by other data than DFS blocks on the datanode in bytes.", 1},
+	{"blockPoolUsed", "block_pool_used_bytes", "Space used by the block pool of this namenode on the datanode in bytes.", 1},
+	{"numBlocks", "blocks", "Number of blocks on the datanode.", 1},
+	{"blockScheduled", "blocks_scheduled", "Number of blocks scheduled to be written to the datanode.", 1},
+	{"volfails", "failed_volumes", "Number of failed volumes of the datanode.", 1},
+	{"lastContact", "last_contact_seconds", "Number of seconds since the last heartbeat of the datanode.", 1},
+	{"lastBlockReport", "last_block_report_seconds", "Number of seconds since the last block report of the datanode.", 60},
+}
+
+var deadNodeFields = []nodeField{
+	{"lastContact", "last_contact_seconds", "Number of seconds since the last heartbeat of the dead datanode.", 1},
+	{"decommissioned", "decommissioned", "Whether the dead datanode is decommissioned (1) or not (0).", 1},
+}
+
+var decomNodeFields = []nodeField{
+	{"underReplicatedBlocks", "under_replicated_blocks", "Number of under-replicated blocks the decommissioning datanode still has to replicate.", 1},
+	{"decommissionOnlyReplicas", "decommission_only_replica_blocks", "Number of blocks of the decommissioning datanode whose replicas are all on decommissioning datanodes.", 1},
+	{"underReplicateInOpenFiles", "under_replicated_open_file_blocks", "Number of under-replicated blocks of open files on the decommissioning datanode.", 1},
+}
+
+// deadNodesCollector maps the DeadNodes and DecomNodes attributes of
+// NameNodeInfo, which only list the dead and decommissioning datanodes.
+var deadNodesCollector = &beanCollector{
+	bean:       nameNodeInfoBean,
+	attributes: []string{"DeadNodes", "DecomNodes"},
+	collect:    collectDeadNodes,
+}
+
+func collectLiveNodes(e *Exporter, bean jmxBean, ch chan<- prometheus.Metric) {
+	var nodes map[string]map[string]interface{}
+	if !bean.decodeJSON("LiveNodes", &nodes) {
+		return
+	}
+
+	info := prometheus.NewDesc(
+		prometheus.BuildFQName(e.module.name, "datanode", "info"),
+		"Information about a live datanode, always 1.",
+		[]string{"datanode", "xferaddr", "admin_state", "version"},
+		e.constLabels,
+	)
+	for _, name := range sortedNodeNames(nodes) {
+		node := nodes[name]
+		xferaddr, _ := node["xferaddr"].(string)
+		adminState, _ := node["adminState"].(string)
+		version, _ := node["version"].(string)
+		ch <- prometheus.MustNewConstMetric(info, prometheus.GaugeValue, 1, name, xferaddr, adminState, version)
+	}
+	collectNodeFields(e, bean, "LiveNodes", nodes, "datanode", liveNodeFields, ch)
+}
+
+func collectDeadNodes(e *Exporter, bean jmxBean, ch chan<- prometheus.Metric) {
+	var dead, decom map[string]map[string]interface{}
+	if bean.decodeJSON("DeadNodes", &dead) {
+		collectNodeFields(e, bean, "DeadNodes", dead, "dead_datanode", deadNodeFields, ch)
+	}
+	if bean.decodeJSON("DecomNodes", &decom) {
+		collectNodeFields(e, bean, "DecomNodes", decom, "decommissioning_datanode", decomNodeFields, ch)
+	}
+}
+
+// collectNodeFields delivers the fields of every node of a node list
+// attribute as gauges labelled by the node name.
+func collectNodeFields(e *Exporter, bean jmxBean, attribute string, nodes map[string]map[string]interface{}, subsystem string, fields []nodeField, ch chan<- prometheus.Metric) {
+	descs := make([]*prometheus.Desc, len(fields))
+	for i, f := range fields {
+		descs[i] = prometheus.NewDesc(
+			prometheus.BuildFQName(e.module.name, subsystem, f.name),
+			f.help,
+			[]string{"datanode"},
+			e.constLabels,
+		)
+	}
+
+	for _, name := range sortedNodeNames(nodes) {
+		node := nodes[name]
+		for i, f := range fields {
+			v, ok := node[f.field]
+			if !ok {
+				// Not every Hadoop version reports every field.
+				continue
+			}
+			value, err := decodeNumber(v)
+			if err != nil {
+				reportAttributeError(bean.name(), attribute, err)
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(descs[i], prometheus.GaugeValue, value*f.scale, name)
+		}
+	}
+}
+
+func sortedNodeNames(nodes map[string]map[string]interface{}) []string {
+	names := make([]string, 0, len(nodes))
+	for name := range nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
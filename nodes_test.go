@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// testNameNodeInfo is an excerpt of the NameNodeInfo bean of a Hadoop 3.3
+// namenode with two live, one dead and one decommissioning datanode.
+var testNameNodeInfo = jmxBean{
+	"name":       nameNodeInfoBean,
+	"LiveNodes":  `{"dn1.example.com:9866":{"infoAddr":"10.0.0.11:9864","infoSecureAddr":"10.0.0.11:0","xferaddr":"10.0.0.11:9866","lastContact":1,"usedSpace":1048576,"adminState":"In Service","nonDfsUsedSpace":2048,"capacity":10737418240,"numBlocks":12,"version":"3.3.6","used":1048576,"remaining":10736367616,"blockScheduled":0,"blockPoolUsed":1048576,"blockPoolUsedPercent":0.009765625,"volfails":0,"lastBlockReport":5},"dn2.example.com:9866":{"infoAddr":"10.0.0.12:9864","infoSecureAddr":"10.0.0.12:0","xferaddr":"10.0.0.12:9866","lastContact":2,"usedSpace":0,"adminState":"Decommission In Progress","nonDfsUsedSpace":0,"capacity":10737418240,"numBlocks":0,"version":"3.3.6","used":0,"remaining":10737418240,"blockScheduled":1,"blockPoolUsed":0,"blockPoolUsedPercent":0.0,"volfails":1,"lastVolumeFailureDate":1700000000000,"estimatedCapacityLostTotal":1073741824,"lastBlockReport":0}}`,
+	"DeadNodes":  `{"dn3.example.com:9866":{"lastContact":630,"decommissioned":false,"adminState":"In Service","xferaddr":"10.0.0.13:9866"}}`,
+	"DecomNodes": `{"dn2.example.com:9866":{"xferaddr":"10.0.0.12:9866","underReplicatedBlocks":7,"decommissionOnlyReplicas":2,"underReplicateInOpenFiles":1}}`,
+}
+
+func collectTestNodes(t *testing.T, c *beanCollector, bean jmxBean) map[string]float64 {
+	e := &Exporter{module: modules["namenode"]}
+	return collectTestMetrics(t, func(ch chan<- prometheus.Metric) {
+		c.collect(e, bean, ch)
+	})
+}
+
+func TestCollectLiveNodes(t *testing.T) {
+	got := collectTestNodes(t, liveNodesCollector, testNameNodeInfo)
+	want := map[string]float64{
+		`namenode_datanode_info{admin_state="In Service",datanode="dn1.example.com:9866",version="3.3.6",xferaddr="10.0.0.11:9866"}`:               1,
+		`namenode_datanode_info{admin_state="Decommission In Progress",datanode="dn2.example.com:9866",version="3.3.6",xferaddr="10.0.0.12:9866"}`: 1,
+	}
+	for _, dn := range []struct {
+		name                                                                                      string
+		capacity, used, remaining, nonDfs, poolUsed, blocks, scheduled, volfails, contact, report float64
+	}{
+		{"dn1.example.com:9866", 10737418240, 1048576, 10736367616, 2048, 1048576, 12, 0, 0, 1, 300},
+		{"dn2.example.com:9866", 10737418240, 0, 10737418240, 0, 0, 0, 1, 1, 2, 0},
+	} {
+		for name, v := range map[string]float64{
+			"capacity_bytes":            dn.capacity,
+			"used_bytes":                dn.used,
+			"remaining_bytes":           dn.remaining,
+			"non_dfs_used_bytes":        dn.nonDfs,
+			"block_pool_used_bytes":     dn.poolUsed,
+			"blocks":                    dn.blocks,
+			"blocks_scheduled":          dn.scheduled,
+			"failed_volumes":            dn.volfails,
+			"last_contact_seconds":      dn.contact,
+			"last_block_report_seconds": dn.report,
+		} {
+			want[fmt.Sprintf("namenode_datanode_%s{datanode=%q}", name, dn.name)] = v
+		}
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestCollectDeadNodes(t *testing.T) {
+	got := collectTestNodes(t, deadNodesCollector, testNameNodeInfo)
+	want := map[string]float64{
+		`namenode_dead_datanode_last_contact_seconds{datanode="dn3.example.com:9866"}`:                         630,
+		`namenode_dead_datanode_decommissioned{datanode="dn3.example.com:9866"}`:                               0,
+		`namenode_decommissioning_datanode_under_replicated_blocks{datanode="dn2.example.com:9866"}`:           7,
+		`namenode_decommissioning_datanode_decommission_only_replica_blocks{datanode="dn2.example.com:9866"}`:  2,
+		`namenode_decommissioning_datanode_under_replicated_open_file_blocks{datanode="dn2.example.com:9866"}`: 1,
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// TestCollectNodesEmpty covers the empty lists of a cluster without any
+// datanodes of a kind, which must not produce any series.
+func TestCollectNodesEmpty(t *testing.T) {
+	bean := jmxBean{"name": nameNodeInfoBean, "LiveNodes": "{}", "DeadNodes": "{}", "DecomNodes": "{}"}
+	for _, c := range []*beanCollector{liveNodesCollector, deadNodesCollector} {
+		if got := collectTestNodes(t, c, bean); len(got) != 0 {
+			t.Errorf("%s: got %v, want no metrics", c.attributes, got)
+		}
+	}
+}
+
+func TestCollectNodesInvalid(t *testing.T) {
+	const bean = "Hadoop:service=NameNode,name=TestCollectNodesInvalid"
+	for _, tc := range []struct {
+		collector *beanCollector
+		bean      jmxBean
+		attribute string
+		reason    string
+		metrics   int
+	}{
+		{liveNodesCollector, jmxBean{"name": bean, "LiveNodes": `{"dn1.example.com:9866":{"capacity":`}, "LiveNodes", reasonInvalidValue, 0},
+		{liveNodesCollector, jmxBean{"name": bean}, "LiveNodes", reasonMissing, 0},
+		{deadNodesCollector, jmxBean{"name": bean, "DeadNodes": `[`, "DecomNodes": "{}"}, "DeadNodes", reasonInvalidValue, 0},
+		{deadNodesCollector, jmxBean{"name": bean, "DeadNodes": "{}", "DecomNodes": 1.0}, "DecomNodes", reasonInvalidType, 0},
+		// A bad field only drops that field.
+		{deadNodesCollector, jmxBean{"name": bean, "DeadNodes": `{"dn3.example.com:9866":{"lastContact":"n/a","decommissioned":true}}`, "DecomNodes": "{}"}, "DeadNodes", reasonInvalidValue, 1},
+	} {
+		before := attributeErrorCount(bean, tc.attribute, tc.reason)
+		got := collectTestNodes(t, tc.collector, tc.bean)
+		if len(got) != tc.metrics {
+			t.Errorf("%v: got %v, want %d metrics", tc.bean, got, tc.metrics)
+		}
+		if n := attributeErrorCount(bean, tc.attribute, tc.reason) - before; n != 1 {
+			t.Errorf("%v: counted %v errors with reason %q, want 1", tc.bean, n, tc.reason)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+var (
+	// Hadoop quantiles, enabled with dfs.metrics.percentiles.intervals, are
+	// exposed as <Name><Interval>s<Percentile>thPercentile<Value> attributes,
+	// e.g. Syncs60s99thPercentileLatencyMicros, next to the number of samples
+	// of the interval in <Name><Interval>sNumOps.
+	percentileRE      = regexp.MustCompile(`^([A-Za-z]+?)(\d+)s(\d+)thPercentile([A-Za-z]*)$`)
+	percentileCountRE = regexp.MustCompile(`^([A-Za-z]+?)(\d+)sNumOps$`)
+
+	camelCaseRE = regexp.MustCompile(`([a-z0-9])([A-Z])`)
+)
+
+// beanNameLabels extract a label from the names of beans with an instance per
+// port or journal, so that their summaries are told apart.
+var beanNameLabels = []struct {
+	re    *regexp.Regexp
+	label string
+}{
+	{regexp.MustCompile(`,name=RpcActivityForPort(\d+)$`), "port"},
+	{regexp.MustCompile(`,name=RpcDetailedActivityForPort(\d+)$`), "port"},
+	{regexp.MustCompile(`,name=Journal-(.+)$`), "journal"},
+}
+
+// Unit suffixes of Hadoop metric names and their scale to seconds.
+var timeUnits = []struct {
+	suffix string
+	scale  float64
+}{
+	{"Nanos", 1e-9},
+	{"Micros", 1e-6},
+	{"Millis", 1e-3},
+	{"Ms", 1e-3},
+}
+
+// percentiles are the quantiles of a Hadoop metric over one interval.
+type percentiles struct {
+	name      string
+	interval  string
+	valueName string
+	count     uint64
+	quantiles map[float64]float64
+}
+
+// collectPercentiles delivers the Hadoop quantiles of any bean as summaries
+// with an interval label, one per metric. Latencies are converted to seconds.
+// The count of a summary is the number of samples in the last interval and
+// its sum is unknown.
+func (e *Exporter) collectPercentiles(bean jmxBean, seen map[string]bool, ch chan<- prometheus.Metric) {
+	beanName := bean.name()
+	groups := map[string]*percentiles{}
+	group := func(name, interval string) *percentiles {
+		key := name + "\xff" + interval
+		p, ok := groups[key]
+		if !ok {
+			p = &percentiles{name: name, interval: interval, quantiles: map[float64]float64{}}
+			groups[key] = p
+		}
+		return p
+	}
+
+	for attribute, v := range bean {
+		if m := percentileRE.FindStringSubmatch(attribute); m != nil {
+			value, err := decodeNumber(v)
+			if err != nil {
+				reportAttributeError(beanName, attribute, err)
+				continue
+			}
+			percentile, _ := strconv.ParseFloat(m[3], 64)
+			p := group(m[1], m[2])
+			p.valueName = m[4]
+			p.quantiles[percentile/100] = value
+		} else if m := percentileCountRE.FindStringSubmatch(attribute); m != nil {
+			value, err := decodeNumber(v)
+			if err != nil {
+				reportAttributeError(beanName, attribute, err)
+				continue
+			}
+			group(m[1], m[2]).count = uint64(value)
+		}
+	}
+
+	keys := make([]string, 0, len(groups))
+	for key, p := range groups {
+		if len(p.quantiles) > 0 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	labelNames, labelValues := []string{"interval"}, []string{}
+	for _, l := range beanNameLabels {
+		if m := l.re.FindStringSubmatch(beanName); m != nil {
+			labelNames = append(labelNames, l.label)
+			labelValues = append(labelValues, m[1])
+			break
+		}
+	}
+
+	for _, key := range keys {
+		p := groups[key]
+		name, scale := percentileMetricName(p.name, p.valueName)
+		name = prometheus.BuildFQName(e.module.name, "", name)
+		values := append([]string{p.interval + "s"}, labelValues...)
+
+		metricKey := name + "\xff" + strings.Join(values, "\xff")
+		if seen[metricKey] {
+			log.Debugf("Dropping duplicate percentiles of %s::%s", beanName, p.name)
+			continue
+		}
+		seen[metricKey] = true
+
+		quantiles := make(map[float64]float64, len(p.quantiles))
+		for q, v := range p.quantiles {
+			quantiles[q] = v * scale
+		}
+		desc := prometheus.NewDesc(
+			name,
+			"Quantiles of the Hadoop "+p.name+p.valueName+" metric over the interval, the count is the number of samples of the interval.",
+			labelNames,
+			e.constLabels,
+		)
+		ch <- prometheus.MustNewConstSummary(desc, p.count, math.NaN(), quantiles, values...)
+	}
+}
+
+// percentileMetricName derives the metric name and the scale of the values
+// from a Hadoop quantile, e.g. Syncs and LatencyMicros give
+// syncs_latency_seconds and 1e-6. Latencies without a unit are milliseconds.
+func percentileMetricName(name, valueName string) (string, float64) {
+	scale, isTime := 1.0, strings.HasPrefix(valueName, "Latency") || strings.HasPrefix(valueName, "Time")
+	if isTime {
+		scale = 1e-3
+	}
+	for _, u := range timeUnits {
+		if strings.HasSuffix(name, u.suffix) {
+			name, scale, isTime = strings.TrimSuffix(name, u.suffix), u.scale, true
+			break
+		}
+		if strings.HasSuffix(valueName, u.suffix) {
+			valueName, scale, isTime = strings.TrimSuffix(valueName, u.suffix), u.scale, true
+			break
+		}
+	}
+
+	metricName := snakeCase(name)
+	if valueName != "" {
+		metricName += "_" + snakeCase(valueName)
+	}
+	if isTime {
+		metricName += "_seconds"
+	}
+	return metricName, scale
+}
+
+// snakeCase turns a CamelCase Hadoop metric name into snake case.
+func snakeCase(s string) string {
+	return strings.ToLower(camelCaseRE.ReplaceAllString(s, "${1}_${2}"))
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestPercentileMetricName(t *testing.T) {
+	for _, tc := range []struct {
+		name, valueName string
+		want            string
+		scale           float64
+	}{
+		{"Syncs", "LatencyMicros", "syncs_latency_seconds", 1e-6},
+		{"RpcQueueTime", "Latency", "rpc_queue_time_latency_seconds", 1e-3},
+		{"RpcProcessingTime", "Latency", "rpc_processing_time_latency_seconds", 1e-3},
+		{"SendDataPacketBlockedOnNetworkNanos", "Latency", "send_data_packet_blocked_on_network_latency_seconds", 1e-9},
+		{"FlushNanos", "Latency", "flush_latency_seconds", 1e-9},
+		{"BlockReport", "LatencyMs", "block_report_latency_seconds", 1e-3},
+		{"PacketsReceived", "Value", "packets_received_value", 1},
+	} {
+		got, scale := percentileMetricName(tc.name, tc.valueName)
+		if got != tc.want || scale != tc.scale {
+			t.Errorf("%s, %s: got %s and %v, want %s and %v", tc.name, tc.valueName, got, scale, tc.want, tc.scale)
+		}
+	}
+}
+
+func collectTestPercentiles(t *testing.T, module string, beans ...jmxBean) map[string]float64 {
+	e := &Exporter{module: modules[module]}
+	return collectTestMetrics(t, func(ch chan<- prometheus.Metric) {
+		seen := map[string]bool{}
+		for _, bean := range beans {
+			e.collectPercentiles(bean, seen, ch)
+		}
+	})
+}
+
+// sameSamples compares samples up to the rounding of the unit conversions.
+func sameSamples(got, want map[string]float64) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for key, w := range want {
+		g, ok := got[key]
+		if !ok || math.Abs(g-w) > 1e-9*math.Abs(w) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestCollectPercentiles(t *testing.T) {
+	got := collectTestPercentiles(t, "namenode",
+		jmxBean{
+			"name":                                 "Hadoop:service=NameNode,name=NameNodeActivity",
+			"SyncsNumOps":                          420.0,
+			"SyncsAvgTime":                         1.5,
+			"Syncs60sNumOps":                       12.0,
+			"Syncs60s50thPercentileLatencyMicros":  800.0,
+			"Syncs60s99thPercentileLatencyMicros":  2500.0,
+			"Syncs300sNumOps":                      40.0,
+			"Syncs300s99thPercentileLatencyMicros": 3000.0,
+			// A count without quantiles doesn't make a summary.
+			"Syncs3600sNumOps": 400.0,
+		},
+		jmxBean{
+			"name":                                      "Hadoop:service=NameNode,name=RpcActivityForPort8020",
+			"RpcQueueTime60sNumOps":                     5.0,
+			"RpcQueueTime60s90thPercentileLatency":      2.0,
+			"RpcProcessingTime60sNumOps":                5.0,
+			"RpcProcessingTime60s90thPercentileLatency": 4.0,
+		},
+		// The same metric of another port is a distinct summary.
+		jmxBean{
+			"name":                                 "Hadoop:service=NameNode,name=RpcActivityForPort8022",
+			"RpcQueueTime60sNumOps":                1.0,
+			"RpcQueueTime60s90thPercentileLatency": 1.0,
+		},
+	)
+	want := map[string]float64{
+		`namenode_syncs_latency_seconds{interval="60s",quantile="0.5"}`:                           0.0008,
+		`namenode_syncs_latency_seconds{interval="60s",quantile="0.99"}`:                          0.0025,
+		`namenode_syncs_latency_seconds_count{interval="60s"}`:                                    12,
+		`namenode_syncs_latency_seconds{interval="300s",quantile="0.99"}`:                         0.003,
+		`namenode_syncs_latency_seconds_count{interval="300s"}`:                                   40,
+		`namenode_rpc_queue_time_latency_seconds{interval="60s",port="8020",quantile="0.9"}`:      0.002,
+		`namenode_rpc_queue_time_latency_seconds_count{interval="60s",port="8020"}`:               5,
+		`namenode_rpc_processing_time_latency_seconds{interval="60s",port="8020",quantile="0.9"}`: 0.004,
+		`namenode_rpc_processing_time_latency_seconds_count{interval="60s",port="8020"}`:          5,
+		`namenode_rpc_queue_time_latency_seconds{interval="60s",port="8022",quantile="0.9"}`:      0.001,
+		`namenode_rpc_queue_time_latency_seconds_count{interval="60s",port="8022"}`:               1,
+	}
+	if !sameSamples(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestCollectPercentilesJournal(t *testing.T) {
+	got := collectTestPercentiles(t, "journalnode", jmxBean{
+		"name":                                "Hadoop:service=JournalNode,name=Journal-ns-1",
+		"Syncs60sNumOps":                      3.0,
+		"Syncs60s95thPercentileLatencyMicros": 1000.0,
+	})
+	want := map[string]float64{
+		`journalnode_syncs_latency_seconds{interval="60s",journal="ns-1",quantile="0.95"}`: 0.001,
+		`journalnode_syncs_latency_seconds_count{interval="60s",journal="ns-1"}`:           3,
+	}
+	if !sameSamples(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// TestCollectPercentilesDuplicates makes sure the first of two beans with
+// the same summary wins, as a registry rejects duplicate series.
+func TestCollectPercentilesDuplicates(t *testing.T) {
+	bean := func(name string, value float64) jmxBean {
+		return jmxBean{"name": name, "Syncs60sNumOps": 1.0, "Syncs60s99thPercentileLatencyMicros": value}
+	}
+	got := collectTestPercentiles(t, "namenode",
+		bean("Hadoop:service=NameNode,name=NameNodeActivity", 1e6),
+		bean("Hadoop:service=NameNode,name=NameNodeActivity2", 2e6),
+	)
+	if v := got[`namenode_syncs_latency_seconds{interval="60s",quantile="0.99"}`]; len(got) != 2 || v != 1 {
+		t.Errorf("got %v, want the quantile of the first bean only", got)
+	}
+}
+
+func TestCollectPercentilesInvalid(t *testing.T) {
+	const bean = "Hadoop:service=NameNode,name=TestCollectPercentilesInvalid"
+	before := attributeErrorCount(bean, "Syncs60s99thPercentileLatencyMicros", reasonNull)
+	got := collectTestPercentiles(t, "namenode", jmxBean{
+		"name":                                bean,
+		"Syncs60sNumOps":                      1.0,
+		"Syncs60s50thPercentileLatencyMicros": 1e6,
+		"Syncs60s99thPercentileLatencyMicros": nil,
+	})
+	want := map[string]float64{
+		`namenode_syncs_latency_seconds{interval="60s",quantile="0.5"}`: 1,
+		`namenode_syncs_latency_seconds_count{interval="60s"}`:          1,
+	}
+	if !sameSamples(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if n := attributeErrorCount(bean, "Syncs60s99thPercentileLatencyMicros", reasonNull) - before; n != 1 {
+		t.Errorf("counted %v errors, want 1", n)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+// startPolling makes the exporter fetch the JMX beans every
+// interval in the background and serve scrapes from the last snapshot,
+// instead of querying the daemon on every scrape. Snapshots older than
+// maxStaleness are not served and report the daemon as down.
+func (e *Exporter) startPolling(interval, maxStaleness time.Duration) {
+	e.mu.Lock()
+	e.polling = true
+	e.maxStaleness = maxStaleness
+	e.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			e.poll()
+			<-ticker.C
+		}
+	}()
+}
+
+// poll fetches a new snapshot, keeping the previous one on failure.
+func (e *Exporter) poll() {
+	beans, err := e.fetch()
+	if err != nil {
+		e.reportFetchError(err)
+		return
+	}
+
+	e.mu.Lock()
+	e.snapshot = &jmxEnvelope{Beans: beans}
+	e.snapshotTime = time.Now()
+	e.mu.Unlock()
+}
+
+// polledSnapshot returns the beans of the last poll unless they are older
+// than maxStaleness, and whether the exporter polls at all.
+func (e *Exporter) polledSnapshot() ([]jmxBean, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if !e.polling {
+		return nil, false
+	}
+	if e.snapshot == nil || (e.maxStaleness > 0 && time.Since(e.snapshotTime) > e.maxStaleness) {
+		return nil, true
+	}
+	return e.snapshot.Beans, true
+}
+
+// collectSnapshot returns the beans of the last snapshot for a scrape, and
+// delivers its age. It returns false if there is no snapshot fresh enough.
+func (e *Exporter) collectSnapshot(ch chan<- prometheus.Metric) ([]jmxBean, bool) {
+	e.mu.RLock()
+	snapshot, snapshotTime, maxStaleness := e.snapshot, e.snapshotTime, e.maxStaleness
+	e.mu.RUnlock()
+
+	if snapshot == nil {
+		log.Errorf("Failed to collect metrics from %s %s: no snapshot polled yet", e.module.name, e.url)
+		return nil, false
+	}
+
+	age := time.Since(snapshotTime)
+	ch <- prometheus.MustNewConstMetric(e.snapshotAge, prometheus.GaugeValue, age.Seconds())
+	if maxStaleness > 0 && age > maxStaleness {
+		log.Errorf("Failed to collect metrics from %s %s: snapshot is %s old", e.module.name, e.url, age)
+		return nil, false
+	}
+	return snapshot.Beans, true
+}
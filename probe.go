@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/log"
+)
+
+// probeHandler returns a handler which scrapes the JMX URL given by the
+// "target" query parameter, in the style of the blackbox exporter. The
+// "module" parameter selects the kind of daemon, defaultModule if missing.
+// Every request builds its own Exporter on a fresh registry, so the
+// exporter's own metrics stay on the default registry only. What is learnt
+// about a target is kept in probeTargets between requests.
+func probeHandler(opts exporterOpts, defaultModule *module) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target, err := normalizeTarget(r.URL.Query().Get("target"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		m := defaultModule
+		if name := r.URL.Query().Get("module"); name != "" {
+			var ok bool
+			if m, ok = modules[name]; !ok {
+				http.Error(w, fmt.Sprintf("unknown module %q", name), http.StatusBadRequest)
+				return
+			}
+		}
+
+		registry := prometheus.NewRegistry()
+		e := NewExporter(target, m, opts, nil)
+		e.target = probeTargets.get(target)
+		registry.MustRegister(e)
+		gathererHandler(registry).ServeHTTP(w, r)
+	})
+}
+
+// normalizeTarget turns the target of a probe request into a JMX URL. A
+// bare "host:port" is accepted and expanded to "http://host:port/jmx".
+func normalizeTarget(target string) (string, error) {
+	if target == "" {
+		return "", fmt.Errorf("'target' parameter must be specified")
+	}
+	if !strings.Contains(target, "://") {
+		target = "http://" + target
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", fmt.Errorf("invalid target %q: %s", target, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("invalid target %q: unsupported scheme %q", target, u.Scheme)
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("invalid target %q: missing host", target)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/jmx"
+	}
+	return u.String(), nil
+}
+
+// gathererHandler returns an HTTP handler exposing the metrics of the given
+// gatherer. It mirrors prometheus.UninstrumentedHandler, which only serves
+// the default gatherer.
+func gathererHandler(g prometheus.Gatherer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mfs, err := g.Gather()
+		if err != nil {
+			log.Errorf("Error gathering metrics: %s", err)
+			if len(mfs) == 0 {
+				http.Error(w, "An error has occurred during metrics collection:\n\n"+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		contentType := expfmt.Negotiate(r.Header)
+		var buf bytes.Buffer
+		enc := expfmt.NewEncoder(&buf, contentType)
+		for _, mf := range mfs {
+			if err := enc.Encode(mf); err != nil {
+				http.Error(w, "An error has occurred during metrics encoding:\n\n"+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", string(contentType))
+		w.Write(buf.Bytes())
+	})
+}
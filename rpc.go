@@ -0,0 +1,165 @@
+package main
+
+import (
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	nameNodeStatusBean            = "Hadoop:service=NameNode,name=NameNodeStatus"
+	rpcDetailedActivityBeanPrefix = "Hadoop:service=NameNode,name=RpcDetailedActivityForPort"
+)
+
+// rpcPortsCollector tells the roles of the RPC servers apart, whose metrics
+// only carry a port label. The client RPC port is the port of the HostAndPort
+// attribute of NameNodeStatus, the service and lifeline ports are only known
+// if the namenode was discovered from the Hadoop configuration.
+var rpcPortsCollector = &beanCollector{
+	bean:       nameNodeStatusBean,
+	attributes: []string{"HostAndPort"},
+	collect:    collectRPCPorts,
+}
+
+func collectRPCPorts(e *Exporter, bean jmxBean, ch chan<- prometheus.Metric) {
+	roles := map[string]string{}
+	for port, role := range e.rpcPorts {
+		roles[port] = role
+	}
+	if v, err := bean.text("HostAndPort"); err != nil {
+		reportAttributeError(bean.name(), "HostAndPort", err)
+	} else if _, port, err := net.SplitHostPort(v); err != nil {
+		reportAttributeError(bean.name(), "HostAndPort", &attributeError{reason: reasonInvalidValue, value: v})
+	} else {
+		roles[port] = "client"
+	}
+
+	info := prometheus.NewDesc(
+		prometheus.BuildFQName(e.module.name, "rpc", "port_info"),
+		"Role of the RPC server listening on the port, one of client, service or lifeline, always 1.",
+		[]string{"port", "role"},
+		e.constLabels,
+	)
+	ports := make([]string, 0, len(roles))
+	for port := range roles {
+		ports = append(ports, port)
+	}
+	sort.Strings(ports)
+	for _, port := range ports {
+		ch <- prometheus.MustNewConstMetric(info, prometheus.GaugeValue, 1, port, roles[port])
+	}
+}
+
+// rpcMethodsCollector maps the RpcDetailedActivityForPort<port> beans, which
+// hold a <Method>NumOps and <Method>AvgTime attribute for every RPC method
+// called since the namenode started. Only methods matching the rpcMethods
+// option are exported to bound the number of series.
+var rpcMethodsCollector = &beanCollector{
+	bean:    rpcDetailedActivityBeanPrefix,
+	prefix:  true,
+	collect: collectRPCMethods,
+}
+
+func collectRPCMethods(e *Exporter, bean jmxBean, ch chan<- prometheus.Metric) {
+	port := strings.TrimPrefix(bean.name(), rpcDetailedActivityBeanPrefix)
+	var (
+		calls = prometheus.NewDesc(
+			prometheus.BuildFQName(e.module.name, "rpc", "method_calls_total"),
+			"Number of calls of the RPC method.",
+			[]string{"port", "method"},
+			e.constLabels,
+		)
+		seconds = prometheus.NewDesc(
+			prometheus.BuildFQName(e.module.name, "rpc", "method_time_seconds_total"),
+			"Time spent processing calls of the RPC method, estimated from the average time of the calls between scrapes.",
+			[]string{"port", "method"},
+			e.constLabels,
+		)
+	)
+
+	attributes := make([]string, 0, len(bean))
+	for attribute := range bean {
+		if strings.HasSuffix(attribute, "NumOps") {
+			attributes = append(attributes, attribute)
+		}
+	}
+	sort.Strings(attributes)
+
+	for _, attribute := range attributes {
+		name := strings.TrimSuffix(attribute, "NumOps")
+		method := lowerFirst(name)
+		if e.rpcMethods != nil && !e.rpcMethods.MatchString(method) {
+			continue
+		}
+
+		n, err := decodeNumber(bean[attribute])
+		if err != nil {
+			reportAttributeError(bean.name(), attribute, err)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(calls, prometheus.CounterValue, n, port, method)
+
+		avg, err := bean.number(name + "AvgTime")
+		if err != nil {
+			reportAttributeError(bean.name(), name+"AvgTime", err)
+			continue
+		}
+		total, ok := e.target.rpcMethodTimes.add(port+"\xff"+method, n, avg/1000)
+		if !ok {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(seconds, prometheus.CounterValue, total, port, method)
+	}
+}
+
+// lowerFirst turns the attribute prefix of an RPC method back into the
+// method name, e.g. GetBlockLocations into getBlockLocations.
+func lowerFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToLower(r)) + s[size:]
+}
+
+// maxRPCMethodTimes bounds the number of methods whose time is estimated
+// per target, as a probed target may report any bean.
+const maxRPCMethodTimes = 2000
+
+// rpcMethodTimes estimates the cumulative time spent in each RPC method. The
+// beans only report the average time of the calls in the last metrics
+// interval, so the calls since the previous scrape are accounted with the
+// current average. It is part of the targetState, so that the estimates
+// also accumulate across probes, which create an exporter per request.
+type rpcMethodTimes struct {
+	mu      sync.Mutex
+	calls   map[string]float64
+	seconds map[string]float64
+}
+
+// add records the number of calls of a method and their current average
+// time in seconds, and returns the estimated total time in seconds. It
+// returns false for new methods once maxRPCMethodTimes are tracked.
+func (t *rpcMethodTimes) add(key string, calls, avg float64) (float64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.calls == nil {
+		t.calls = map[string]float64{}
+		t.seconds = map[string]float64{}
+	}
+	last, ok := t.calls[key]
+	switch {
+	case !ok && len(t.calls) >= maxRPCMethodTimes:
+		return 0, false
+	case !ok || calls < last:
+		// First scrape or a restarted namenode.
+		t.seconds[key] = calls * avg
+	default:
+		t.seconds[key] += (calls - last) * avg
+	}
+	t.calls[key] = calls
+	return t.seconds[key], true
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRPCMethodTimes(t *testing.T) {
+	var times rpcMethodTimes
+	for _, step := range []struct {
+		calls, avg, want float64
+	}{
+		{10, 0.5, 5},
+		{14, 0.25, 6},
+		{14, 2, 6},
+		// The namenode restarted.
+		{2, 1, 2},
+	} {
+		got, ok := times.add("8020\xffgetBlockLocations", step.calls, step.avg)
+		if !ok || got != step.want {
+			t.Errorf("%v calls at %vs: got %v (%t), want %v", step.calls, step.avg, got, ok, step.want)
+		}
+	}
+}
+
+func TestRPCMethodTimesBound(t *testing.T) {
+	var times rpcMethodTimes
+	for i := 0; i < maxRPCMethodTimes; i++ {
+		if _, ok := times.add(fmt.Sprintf("8020\xffmethod%d", i), 1, 1); !ok {
+			t.Fatalf("method %d was not tracked", i)
+		}
+	}
+	if _, ok := times.add("8020\xffoneTooMany", 1, 1); ok {
+		t.Error("got more than maxRPCMethodTimes methods tracked")
+	}
+	if got, ok := times.add("8020\xffmethod0", 3, 1); !ok || got != 3 {
+		t.Errorf("got %v (%t) for a tracked method, want 3", got, ok)
+	}
+}
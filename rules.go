@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	labelNameRE         = regexp.MustCompile("^[a-zA-Z_][a-zA-Z0-9_]*$")
+	invalidMetricCharRE = regexp.MustCompile("[^a-zA-Z0-9_:]")
+)
+
+// rulesConfig is the content of a rules file. The top-level rules are those
+// of the namenode module, the rules of other modules are given in the
+// modules section. Modules without rules keep their built-in ones.
+type rulesConfig struct {
+	Rules   []*rule                 `yaml:"rules"`
+	Modules map[string]*moduleRules `yaml:"modules"`
+}
+
+// moduleRules are the rules of a single module.
+type moduleRules struct {
+	Rules []*rule `yaml:"rules"`
+}
+
+// rule maps the attributes of JMX beans to a Prometheus metric, in the spirit
+// of the jmx_exporter rules.
+//
+// Bean and Attribute are anchored regular expressions matched against the
+// bean name and the attribute name. Name and the values of Labels are
+// templates which may refer to capture groups of both patterns, numbered
+// from the bean pattern to the attribute pattern ($1, $2, ...) or by name
+// (${port}). Name is prefixed with the exporter namespace.
+//
+// Query is an optional JMX object name pattern used to fetch the beans of
+// the rule, it is only needed if none can be derived from Bean.
+type rule struct {
+	Bean      string             `yaml:"bean"`
+	Attribute string             `yaml:"attribute"`
+	Name      string             `yaml:"name"`
+	Help      string             `yaml:"help"`
+	Type      string             `yaml:"type"`
+	Labels    map[string]string  `yaml:"labels"`
+	Scale     float64            `yaml:"scale"`
+	ValueMap  map[string]float64 `yaml:"value_map"`
+	Default   *float64           `yaml:"default"`
+	Query     string             `yaml:"query"`
+
+	beanRegexp       *regexp.Regexp
+	literalBean      string
+	matchRegexp      *regexp.Regexp
+	literalAttribute string
+	valueType        prometheus.ValueType
+	labelNames       []string
+}
+
+// loadRulesFile reads the mapping rules of each module from a YAML file.
+func loadRulesFile(path string) (map[string][]*rule, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't read rules file %q: %s", path, err)
+	}
+	rules, err := parseRules(content)
+	if err != nil {
+		return nil, fmt.Errorf("can't load rules file %q: %s", path, err)
+	}
+	return rules, nil
+}
+
+// parseRules parses and compiles the YAML mapping rules of each module.
+func parseRules(content []byte) (map[string][]*rule, error) {
+	var config rulesConfig
+	if err := yaml.UnmarshalStrict(content, &config); err != nil {
+		return nil, err
+	}
+
+	rules := map[string][]*rule{}
+	if config.Rules != nil {
+		rules["namenode"] = config.Rules
+	}
+	for name, m := range config.Modules {
+		if _, ok := modules[name]; !ok {
+			return nil, fmt.Errorf("unknown module %q", name)
+		}
+		if _, ok := rules[name]; ok {
+			return nil, fmt.Errorf("rules of module %q given twice", name)
+		}
+		if m != nil {
+			rules[name] = m.Rules
+		}
+	}
+
+	for name, moduleRules := range rules {
+		if err := compileRules(moduleRules); err != nil {
+			return nil, fmt.Errorf("module %q: %s", name, err)
+		}
+	}
+	return rules, nil
+}
+
+// parseModuleRules parses and compiles YAML mapping rules of one module.
+func parseModuleRules(content []byte) ([]*rule, error) {
+	var config moduleRules
+	if err := yaml.UnmarshalStrict(content, &config); err != nil {
+		return nil, err
+	}
+	if err := compileRules(config.Rules); err != nil {
+		return nil, err
+	}
+	return config.Rules, nil
+}
+
+func compileRules(rules []*rule) error {
+	for i, r := range rules {
+		if err := r.compile(); err != nil {
+			return fmt.Errorf("rule %d (%q): %s", i, r.Name, err)
+		}
+	}
+	return nil
+}
+
+func (r *rule) compile() error {
+	if r.Bean == "" {
+		return fmt.Errorf("missing bean pattern")
+	}
+	if r.Name == "" {
+		return fmt.Errorf("missing metric name")
+	}
+	if r.Attribute == "" {
+		r.Attribute = ".*"
+	}
+	if r.Help == "" {
+		r.Help = fmt.Sprintf("Hadoop JMX %s attribute of %s.", r.Attribute, r.Bean)
+	}
+	if r.Scale == 0 {
+		r.Scale = 1
+	}
+
+	switch r.Type {
+	case "gauge":
+		r.valueType = prometheus.GaugeValue
+	case "counter":
+		r.valueType = prometheus.CounterValue
+	case "", "untyped":
+		r.valueType = prometheus.UntypedValue
+	default:
+		return fmt.Errorf("unknown metric type %q", r.Type)
+	}
+
+	var err error
+	if r.beanRegexp, err = regexp.Compile("^(?:" + r.Bean + ")$"); err != nil {
+		return fmt.Errorf("invalid bean pattern: %s", err)
+	}
+	if prefix, complete := r.beanRegexp.LiteralPrefix(); complete {
+		r.literalBean = prefix
+	}
+	attrRegexp, err := regexp.Compile("^(?:" + r.Attribute + ")$")
+	if err != nil {
+		return fmt.Errorf("invalid attribute pattern: %s", err)
+	}
+	if prefix, complete := attrRegexp.LiteralPrefix(); complete {
+		r.literalAttribute = prefix
+	}
+	if r.matchRegexp, err = regexp.Compile("^(?:" + r.Bean + ")::(?:" + r.Attribute + ")$"); err != nil {
+		return err
+	}
+
+	for name := range r.Labels {
+		if !labelNameRE.MatchString(name) {
+			return fmt.Errorf("invalid label name %q", name)
+		}
+		r.labelNames = append(r.labelNames, name)
+	}
+	sort.Strings(r.labelNames)
+	return nil
+}
+
+// match reports whether the rule applies to the given bean attribute, and
+// returns the capture groups of the match.
+func (r *rule) match(beanName, attribute string) ([]int, bool) {
+	m := r.matchRegexp.FindStringSubmatchIndex(beanName + "::" + attribute)
+	return m, m != nil
+}
+
+// value converts an attribute value. Strings are looked up in the value map
+// first, everything else is decoded as a number.
+func (r *rule) value(v interface{}) (float64, error) {
+	if s, ok := v.(string); ok && r.ValueMap != nil {
+		if mapped, ok := r.ValueMap[s]; ok {
+			return mapped, nil
+		}
+		if r.Default != nil {
+			return *r.Default, nil
+		}
+	}
+	f, err := decodeNumber(v)
+	return f * r.Scale, err
+}
+
+// metric builds the metric for a matched bean attribute. It also returns a
+// key identifying the metric by name and label values, which is used to drop
+// duplicates produced by overlapping rules.
+func (r *rule) metric(namespace string, constLabels prometheus.Labels, beanName, attribute string, match []int, value float64) (prometheus.Metric, string, error) {
+	src := beanName + "::" + attribute
+	name := string(r.matchRegexp.ExpandString(nil, r.Name, src, match))
+	name = prometheus.BuildFQName(namespace, "", invalidMetricCharRE.ReplaceAllString(name, "_"))
+
+	labelValues := make([]string, 0, len(r.labelNames))
+	for _, label := range r.labelNames {
+		labelValues = append(labelValues, string(r.matchRegexp.ExpandString(nil, r.Labels[label], src, match)))
+	}
+	key := name + "\xff" + strings.Join(labelValues, "\xff")
+
+	desc := prometheus.NewDesc(name, r.Help, r.labelNames, constLabels)
+	m, err := prometheus.NewConstMetric(desc, r.valueType, value, labelValues...)
+	return m, key, err
+}
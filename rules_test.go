@@ -0,0 +1,331 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+var descNameRE = regexp.MustCompile(`fqName: "([^"]+)"`)
+
+// collectTestMetrics runs collect and returns the delivered samples keyed as
+// in the exposition format, e.g. `namenode_rpc_calls_total{port="8020"}`.
+// Summaries are returned as their quantiles and _count.
+func collectTestMetrics(t *testing.T, collect func(ch chan<- prometheus.Metric)) map[string]float64 {
+	ch := make(chan prometheus.Metric)
+	go func() {
+		collect(ch)
+		close(ch)
+	}()
+
+	samples := map[string]float64{}
+	for m := range ch {
+		name := descNameRE.FindStringSubmatch(m.Desc().String())[1]
+		var pb dto.Metric
+		if err := m.Write(&pb); err != nil {
+			t.Fatalf("invalid metric %s: %s", name, err)
+		}
+		var labels []string
+		for _, l := range pb.Label {
+			labels = append(labels, fmt.Sprintf("%s=%q", l.GetName(), l.GetValue()))
+		}
+		key := func(name string, extra ...string) string {
+			all := append(append([]string{}, labels...), extra...)
+			sort.Strings(all)
+			return name + "{" + strings.Join(all, ",") + "}"
+		}
+		add := func(key string, value float64) {
+			if _, ok := samples[key]; ok {
+				t.Errorf("duplicate sample %s", key)
+			}
+			samples[key] = value
+		}
+		switch {
+		case pb.Gauge != nil:
+			add(key(name), pb.Gauge.GetValue())
+		case pb.Counter != nil:
+			add(key(name), pb.Counter.GetValue())
+		case pb.Untyped != nil:
+			add(key(name), pb.Untyped.GetValue())
+		case pb.Summary != nil:
+			for _, q := range pb.Summary.Quantile {
+				add(key(name, fmt.Sprintf("quantile=%q", fmt.Sprint(q.GetQuantile()))), q.GetValue())
+			}
+			add(key(name+"_count"), float64(pb.Summary.GetSampleCount()))
+		default:
+			t.Fatalf("unexpected metric type of %s", name)
+		}
+	}
+	return samples
+}
+
+// attributeErrorCount returns the number of attribute errors counted so far.
+func attributeErrorCount(bean, attribute, reason string) float64 {
+	var pb dto.Metric
+	attributeErrors.WithLabelValues(bean, attribute, reason).Write(&pb)
+	return pb.Counter.GetValue()
+}
+
+func mustParseRules(t *testing.T, content string) []*rule {
+	rules, err := parseModuleRules([]byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rules
+}
+
+// collectTestBeans maps beans with the given rules of the namenode module.
+func collectTestBeans(t *testing.T, rules []*rule, beans ...jmxBean) map[string]float64 {
+	e := &Exporter{module: modules["namenode"], rules: rules}
+	return collectTestMetrics(t, func(ch chan<- prometheus.Metric) {
+		seen := map[string]bool{}
+		for _, bean := range beans {
+			e.collectBean(bean, seen, ch)
+		}
+	})
+}
+
+func TestRuleLiteralPrefixes(t *testing.T) {
+	for _, tc := range []struct {
+		bean, attribute               string
+		literalBean, literalAttribute string
+	}{
+		{"Hadoop:service=NameNode,name=FSNamesystem", "BlocksTotal", "Hadoop:service=NameNode,name=FSNamesystem", "BlocksTotal"},
+		{`java\.lang:type=Runtime`, "Uptime", "java.lang:type=Runtime", "Uptime"},
+		{`java\.lang:type=GarbageCollector,name=(.+)`, "CollectionCount", "", "CollectionCount"},
+		{`Hadoop:service=NameNode,name=RpcActivityForPort(\d+)`, `(\w+)NumOps`, "", ""},
+		{"Hadoop:service=NameNode,name=NameNodeActivity", "", "Hadoop:service=NameNode,name=NameNodeActivity", ""},
+		// Alternatives have no single literal.
+		{"Hadoop:service=NameNode,name=(?:FSNamesystem|FSNamesystemState)", "A|B", "", ""},
+	} {
+		r := &rule{Bean: tc.bean, Attribute: tc.attribute, Name: "x"}
+		if err := r.compile(); err != nil {
+			t.Fatalf("%s::%s: %s", tc.bean, tc.attribute, err)
+		}
+		if r.literalBean != tc.literalBean || r.literalAttribute != tc.literalAttribute {
+			t.Errorf("%s::%s: got literals %q and %q, want %q and %q", tc.bean, tc.attribute, r.literalBean, r.literalAttribute, tc.literalBean, tc.literalAttribute)
+		}
+	}
+}
+
+func TestRulePatternsAreAnchored(t *testing.T) {
+	rules := mustParseRules(t, `
+rules:
+  - bean: 'Hadoop:service=NameNode,name=FSNamesystem'
+    attribute: Blocks
+    name: blocks
+`)
+	got := collectTestBeans(t, rules,
+		jmxBean{"name": "Hadoop:service=NameNode,name=FSNamesystem", "Blocks": 1.0, "BlocksTotal": 2.0, "MissingBlocks": 3.0},
+		jmxBean{"name": "Hadoop:service=NameNode,name=FSNamesystemState", "Blocks": 4.0},
+		jmxBean{"name": "x-Hadoop:service=NameNode,name=FSNamesystem", "Blocks": 5.0},
+	)
+	want := map[string]float64{"namenode_blocks{}": 1}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestRuleTemplates(t *testing.T) {
+	rules := mustParseRules(t, `
+rules:
+  - bean: 'Hadoop:service=NameNode,name=RpcActivityForPort(\d+)'
+    attribute: '(\w+)NumOps'
+    name: rpc_${1}_${2}_total
+    type: counter
+    labels: {port: $1, op: $2}
+  - bean: 'Hadoop:service=NameNode,name=Journal-(?P<journal>.+)'
+    attribute: 'Last(\w+)'
+    name: journal_last
+    labels: {journal: '${journal}', what: $2}
+    scale: 0.5
+`)
+	got := collectTestBeans(t, rules,
+		jmxBean{"name": "Hadoop:service=NameNode,name=RpcActivityForPort8020", "RpcQueueTimeNumOps": 10.0, "CallQueueLength": 3.0},
+		jmxBean{"name": "Hadoop:service=NameNode,name=Journal-ns-1", "LastPromisedEpoch": 4.0},
+	)
+	want := map[string]float64{
+		`namenode_rpc_8020_RpcQueueTime_total{op="RpcQueueTime",port="8020"}`: 10,
+		`namenode_journal_last{journal="ns-1",what="PromisedEpoch"}`:          2,
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestRuleValues(t *testing.T) {
+	zero, one := 0.0, 1.0
+	for _, tc := range []struct {
+		name     string
+		valueMap map[string]float64
+		def      *float64
+		value    interface{}
+		want     float64
+		err      bool
+	}{
+		{"number", nil, nil, 2.0, 2, false},
+		{"numeric string", nil, nil, "2", 2, false},
+		{"value map hit", map[string]float64{"active": 1}, &zero, "active", 1, false},
+		{"value map miss with default", map[string]float64{"active": 1}, &zero, "standby", 0, false},
+		{"value map miss without default", map[string]float64{"active": 1}, nil, "standby", 0, true},
+		{"value map miss of a number", map[string]float64{"active": 1}, &one, 3.0, 3, false},
+		{"default without value map", nil, &one, "standby", 0, true},
+	} {
+		r := &rule{Bean: "b", Name: "x", ValueMap: tc.valueMap, Default: tc.def}
+		if err := r.compile(); err != nil {
+			t.Fatal(err)
+		}
+		got, err := r.value(tc.value)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%s: got %v, want an error", tc.name, got)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("%s: got %v (%v), want %v", tc.name, got, err, tc.want)
+		}
+	}
+}
+
+func TestRuleValueMap(t *testing.T) {
+	rules := mustParseRules(t, `
+rules:
+  - bean: 'Hadoop:service=NameNode,name=NameNodeStatus'
+    attribute: State
+    name: state
+    value_map: {active: 1}
+    default: 0
+`)
+	for state, want := range map[string]float64{"active": 1, "standby": 0, "observer": 0} {
+		got := collectTestBeans(t, rules, jmxBean{"name": "Hadoop:service=NameNode,name=NameNodeStatus", "State": state})
+		if got["namenode_state{}"] != want || len(got) != 1 {
+			t.Errorf("%s: got %v, want %v", state, got, want)
+		}
+	}
+}
+
+func TestRuleDuplicateMetrics(t *testing.T) {
+	rules := mustParseRules(t, `
+rules:
+  - bean: 'Hadoop:service=NameNode,name=(FSNamesystem|FSNamesystemState)'
+    attribute: BlocksTotal
+    name: blocks_total
+  - bean: 'Hadoop:service=NameNode,name=FSNamesystem'
+    attribute: '.*Blocks'
+    name: blocks
+    labels: {kind: all}
+`)
+	got := collectTestBeans(t, rules,
+		jmxBean{"name": "Hadoop:service=NameNode,name=FSNamesystem", "BlocksTotal": 1.0, "CorruptBlocks": 2.0, "MissingBlocks": 3.0},
+		jmxBean{"name": "Hadoop:service=NameNode,name=FSNamesystemState", "BlocksTotal": 4.0},
+	)
+	// The first of the duplicates wins, in the order of the beans and of the
+	// attribute names.
+	want := map[string]float64{
+		`namenode_blocks_total{}`:     1,
+		`namenode_blocks{kind="all"}`: 2,
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestRuleFirstMatchWins(t *testing.T) {
+	rules := mustParseRules(t, `
+rules:
+  - bean: 'Hadoop:service=NameNode,name=FSNamesystem'
+    attribute: BlocksTotal
+    name: blocks_total
+  - bean: 'Hadoop:service=NameNode,name=FSNamesystem'
+    name: fs_$1
+    attribute: '(\w+)'
+`)
+	got := collectTestBeans(t, rules, jmxBean{"name": "Hadoop:service=NameNode,name=FSNamesystem", "BlocksTotal": 1.0, "FilesTotal": 2.0})
+	want := map[string]float64{`namenode_blocks_total{}`: 1, `namenode_fs_FilesTotal{}`: 2}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestRuleMissingAttribute(t *testing.T) {
+	rules := mustParseRules(t, `
+rules:
+  - bean: 'Hadoop:service=NameNode,name=FSNamesystem'
+    attribute: MissingTotal
+    name: missing_total
+`)
+	bean := "Hadoop:service=NameNode,name=FSNamesystem"
+	before := attributeErrorCount(bean, "MissingTotal", reasonMissing)
+	collectTestBeans(t, rules, jmxBean{"name": bean, "BlocksTotal": 1.0})
+	if got := attributeErrorCount(bean, "MissingTotal", reasonMissing) - before; got != 1 {
+		t.Errorf("got %v missing attribute errors, want 1", got)
+	}
+}
+
+func TestParseRulesErrors(t *testing.T) {
+	for name, content := range map[string]string{
+		"unknown field":     "rules:\n  - bean: b\n    name: x\n    unknown: 1\n",
+		"missing bean":      "rules:\n  - name: x\n",
+		"missing name":      "rules:\n  - bean: b\n",
+		"invalid type":      "rules:\n  - bean: b\n    name: x\n    type: histogram\n",
+		"invalid bean":      "rules:\n  - bean: '('\n    name: x\n",
+		"invalid attribute": "rules:\n  - bean: b\n    attribute: '['\n    name: x\n",
+		"invalid label":     "rules:\n  - bean: b\n    name: x\n    labels: {0a: x}\n",
+		"unknown module":    "modules:\n  secondarynamenode:\n    rules: []\n",
+		"namenode twice":    "rules: []\nmodules:\n  namenode:\n    rules: []\n",
+	} {
+		if _, err := parseRules([]byte(content)); err == nil {
+			t.Errorf("%s: got no error", name)
+		}
+	}
+}
+
+// TestDefaultRulesUptime makes sure the JVM uptime and start time, which are
+// reported in milliseconds, are exported in seconds by every module.
+func TestDefaultRulesUptime(t *testing.T) {
+	for name, m := range modules {
+		rules, err := parseModuleRules([]byte(m.defaultRules()))
+		if err != nil {
+			t.Fatalf("%s: can't parse default rules: %s", name, err)
+		}
+		e := &Exporter{module: m, rules: rules}
+		got := collectTestMetrics(t, func(ch chan<- prometheus.Metric) {
+			e.collectBean(jmxBean{"name": "java.lang:type=Runtime", "Uptime": 90000.0, "StartTime": 1700000000000.0}, map[string]bool{}, ch)
+		})
+		want := map[string]float64{name + "_uptime_seconds{}": 90, name + "_jvm_start_time_seconds{}": 1700000000}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%s: got %v, want %v", name, got, want)
+		}
+	}
+}
+
+// TestDefaultRulesGCLabels makes sure the collections counted by JvmMetrics
+// and by the platform beans carry the same collector label, as JvmMetrics
+// keeps the collector names as they are.
+func TestDefaultRulesGCLabels(t *testing.T) {
+	m := modules["namenode"]
+	rules, err := parseModuleRules([]byte(m.defaultRules()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := collectTestBeans(t, rules,
+		jmxBean{"name": "Hadoop:service=NameNode,name=JvmMetrics", "GcCountG1 Young Generation": 7.0, "GcTimeMillisG1 Young Generation": 1500.0},
+		jmxBean{"name": "java.lang:type=GarbageCollector,name=G1 Young Generation", "CollectionCount": 7.0, "CollectionTime": 1500.0},
+	)
+	for _, key := range []string{
+		`namenode_jvm_gc_count_total{collector="G1 Young Generation"}`,
+		`namenode_jvm_gc_time_seconds_total{collector="G1 Young Generation"}`,
+		`namenode_jvm_gc_collections_total{collector="G1 Young Generation"}`,
+		`namenode_jvm_gc_collection_seconds_total{collector="G1 Young Generation"}`,
+	} {
+		if _, ok := got[key]; !ok {
+			t.Errorf("missing %s in %v", key, got)
+		}
+	}
+}
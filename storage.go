@@ -0,0 +1,92 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// storageCollector maps the NameDirStatuses and NameJournalStatus
+// attributes of NameNodeInfo, which hold the state of the name directories
+// and of the journals the namenode writes its edits to.
+var storageCollector = &beanCollector{
+	bean:       nameNodeInfoBean,
+	attributes: []string{"NameDirStatuses", "NameJournalStatus"},
+	collect:    collectStorage,
+}
+
+// journalStatus is the state of a single journal, all fields are strings.
+type journalStatus struct {
+	Manager  string `json:"manager"`
+	Stream   string `json:"stream"`
+	Disabled string `json:"disabled"`
+	Required string `json:"required"`
+}
+
+// Streams reported for journals which aren't being written to. The stream of
+// a disabled journal is "Failed".
+var idleJournalStreams = map[string]bool{
+	"not currently writing": true,
+	"open for read":         true,
+	"Failed":                true,
+}
+
+func collectStorage(e *Exporter, bean jmxBean, ch chan<- prometheus.Metric) {
+	// Maps "active" and "failed" to the directories and their storage type.
+	var dirs map[string]map[string]string
+	if bean.decodeJSON("NameDirStatuses", &dirs) {
+		active := prometheus.NewDesc(
+			prometheus.BuildFQName(e.module.name, "name_dir", "active"),
+			"Whether the name directory is active (1) or failed (0).",
+			[]string{"dir", "type"},
+			e.constLabels,
+		)
+		for _, status := range []string{"active", "failed"} {
+			value := 0.0
+			if status == "active" {
+				value = 1
+			}
+			paths := make([]string, 0, len(dirs[status]))
+			for path := range dirs[status] {
+				paths = append(paths, path)
+			}
+			sort.Strings(paths)
+			for _, path := range paths {
+				ch <- prometheus.MustNewConstMetric(active, prometheus.GaugeValue, value, path, dirs[status][path])
+			}
+		}
+	}
+
+	var journals []journalStatus
+	if bean.decodeJSON("NameJournalStatus", &journals) {
+		newDesc := func(name, help string) *prometheus.Desc {
+			return prometheus.NewDesc(prometheus.BuildFQName(e.module.name, "journal", name), help, []string{"journal"}, e.constLabels)
+		}
+		var (
+			disabled = newDesc("disabled", "Whether the journal is disabled after a failure (1) or not (0).")
+			required = newDesc("required", "Whether the journal is required for the namenode to keep running (1) or not (0).")
+			writing  = newDesc("writing", "Whether the namenode is writing edits to the journal (1) or not (0).")
+		)
+		for _, j := range journals {
+			for _, m := range []struct {
+				desc  *prometheus.Desc
+				value string
+			}{
+				{disabled, j.Disabled},
+				{required, j.Required},
+			} {
+				value, err := decodeNumber(m.value)
+				if err != nil {
+					reportAttributeError(bean.name(), "NameJournalStatus", err)
+					continue
+				}
+				ch <- prometheus.MustNewConstMetric(m.desc, prometheus.GaugeValue, value, j.Manager)
+			}
+			value := 1.0
+			if disabled, _ := decodeNumber(j.Disabled); idleJournalStreams[j.Stream] || disabled != 0 {
+				value = 0
+			}
+			ch <- prometheus.MustNewConstMetric(writing, prometheus.GaugeValue, value, j.Manager)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func collectTestStorage(t *testing.T, bean jmxBean) map[string]float64 {
+	e := &Exporter{module: modules["namenode"]}
+	return collectTestMetrics(t, func(ch chan<- prometheus.Metric) {
+		collectStorage(e, bean, ch)
+	})
+}
+
+const (
+	testQJM       = "QJM to [10.0.0.21:8485, 10.0.0.22:8485, 10.0.0.23:8485]"
+	testFileJM    = "FileJournalManager(root=/data/1/dfs/name)"
+	testFailedJM  = "FileJournalManager(root=/data/2/dfs/name)"
+	testDirStatus = `{"active":{"/data/1/dfs/name":"IMAGE_AND_EDITS"},"failed":{"/data/2/dfs/name":"IMAGE_AND_EDITS"}}`
+)
+
+func TestCollectStorage(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		journals string
+		want     map[string]float64
+	}{
+		{
+			name:     "active",
+			journals: `[{"stream":"Writing segment beginning at txid 1234. \n10.0.0.21:8485 (Written txid 1300), 10.0.0.22:8485 (Written txid 1300), 10.0.0.23:8485 (Written txid 1299)","manager":"` + testQJM + `","required":"true","disabled":"false"},{"stream":"EditLogFileOutputStream(/data/1/dfs/name/current/edits_inprogress_0000000000000001234)","manager":"` + testFileJM + `","required":"false","disabled":"false"}]`,
+			want: map[string]float64{
+				fmt.Sprintf("namenode_journal_disabled{journal=%q}", testQJM):    0,
+				fmt.Sprintf("namenode_journal_required{journal=%q}", testQJM):    1,
+				fmt.Sprintf("namenode_journal_writing{journal=%q}", testQJM):     1,
+				fmt.Sprintf("namenode_journal_disabled{journal=%q}", testFileJM): 0,
+				fmt.Sprintf("namenode_journal_required{journal=%q}", testFileJM): 0,
+				fmt.Sprintf("namenode_journal_writing{journal=%q}", testFileJM):  1,
+			},
+		},
+		{
+			name:     "standby",
+			journals: `[{"stream":"open for read","manager":"` + testQJM + `","required":"true","disabled":"false"},{"stream":"not currently writing","manager":"` + testFileJM + `","required":"false","disabled":"false"}]`,
+			want: map[string]float64{
+				fmt.Sprintf("namenode_journal_disabled{journal=%q}", testQJM):    0,
+				fmt.Sprintf("namenode_journal_required{journal=%q}", testQJM):    1,
+				fmt.Sprintf("namenode_journal_writing{journal=%q}", testQJM):     0,
+				fmt.Sprintf("namenode_journal_disabled{journal=%q}", testFileJM): 0,
+				fmt.Sprintf("namenode_journal_required{journal=%q}", testFileJM): 0,
+				fmt.Sprintf("namenode_journal_writing{journal=%q}", testFileJM):  0,
+			},
+		},
+		{
+			// A journal which failed is disabled and no longer written to.
+			name:     "failed",
+			journals: `[{"stream":"Failed","manager":"` + testFailedJM + `","required":"false","disabled":"true"}]`,
+			want: map[string]float64{
+				fmt.Sprintf("namenode_journal_disabled{journal=%q}", testFailedJM): 1,
+				fmt.Sprintf("namenode_journal_required{journal=%q}", testFailedJM): 0,
+				fmt.Sprintf("namenode_journal_writing{journal=%q}", testFailedJM):  0,
+			},
+		},
+		{
+			// The stream of a disabled journal isn't necessarily "Failed".
+			name:     "disabled",
+			journals: `[{"stream":"EditLogFileOutputStream(/data/2/dfs/name/current/edits_inprogress_0000000000000001234)","manager":"` + testFailedJM + `","required":"false","disabled":"true"}]`,
+			want: map[string]float64{
+				fmt.Sprintf("namenode_journal_disabled{journal=%q}", testFailedJM): 1,
+				fmt.Sprintf("namenode_journal_required{journal=%q}", testFailedJM): 0,
+				fmt.Sprintf("namenode_journal_writing{journal=%q}", testFailedJM):  0,
+			},
+		},
+	} {
+		got := collectTestStorage(t, jmxBean{"name": nameNodeInfoBean, "NameDirStatuses": testDirStatus, "NameJournalStatus": tc.journals})
+		tc.want[`namenode_name_dir_active{dir="/data/1/dfs/name",type="IMAGE_AND_EDITS"}`] = 1
+		tc.want[`namenode_name_dir_active{dir="/data/2/dfs/name",type="IMAGE_AND_EDITS"}`] = 0
+		if fmt.Sprint(got) != fmt.Sprint(tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestCollectStorageEmpty(t *testing.T) {
+	got := collectTestStorage(t, jmxBean{"name": nameNodeInfoBean, "NameDirStatuses": `{"active":{},"failed":{}}`, "NameJournalStatus": "[]"})
+	if len(got) != 0 {
+		t.Errorf("got %v, want no metrics", got)
+	}
+}
+
+func TestCollectStorageInvalid(t *testing.T) {
+	const bean = "Hadoop:service=NameNode,name=TestCollectStorageInvalid"
+	for _, tc := range []struct {
+		bean      jmxBean
+		attribute string
+		reason    string
+		metrics   int
+	}{
+		{jmxBean{"name": bean, "NameDirStatuses": `{"active":`, "NameJournalStatus": "[]"}, "NameDirStatuses", reasonInvalidValue, 0},
+		{jmxBean{"name": bean, "NameDirStatuses": testDirStatus, "NameJournalStatus": `[{"stream":`}, "NameJournalStatus", reasonInvalidValue, 2},
+		{jmxBean{"name": bean, "NameDirStatuses": testDirStatus}, "NameJournalStatus", reasonMissing, 2},
+		// An unexpected flag only drops that metric.
+		{jmxBean{"name": bean, "NameDirStatuses": "{}", "NameJournalStatus": `[{"stream":"open for read","manager":"` + testQJM + `","required":"yes","disabled":"false"}]`}, "NameJournalStatus", reasonInvalidValue, 2},
+	} {
+		before := attributeErrorCount(bean, tc.attribute, tc.reason)
+		got := collectTestStorage(t, tc.bean)
+		if len(got) != tc.metrics {
+			t.Errorf("%v: got %v, want %d metrics", tc.bean, got, tc.metrics)
+		}
+		if n := attributeErrorCount(bean, tc.attribute, tc.reason) - before; n != 1 {
+			t.Errorf("%v: counted %v errors with reason %q, want 1", tc.bean, n, tc.reason)
+		}
+	}
+}
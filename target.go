@@ -0,0 +1,68 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// probeTargetTTL is how long the state of a probed target is kept after
+	// its last probe.
+	probeTargetTTL = 10 * time.Minute
+	// maxProbeTargets bounds the number of probed targets whose state is
+	// kept, as the targets are chosen by the callers of /probe.
+	maxProbeTargets = 1000
+)
+
+// targetState is what an exporter learns about its JMX URL across scrapes.
+type targetState struct {
+	// queryUnsupported is set once the JMX servlet turned out to ignore or
+	// reject the qry parameter.
+	queryUnsupported int32
+	rpcMethodTimes   rpcMethodTimes
+
+	lastUsed time.Time
+}
+
+// targetStates keeps the state of the probed targets, which would otherwise
+// be lost with the exporter created for every probe request.
+type targetStates struct {
+	mu     sync.Mutex
+	states map[string]*targetState
+}
+
+var probeTargets targetStates
+
+// get returns the state of the given JMX URL, creating it if needed.
+// States unused for probeTargetTTL are dropped, and the least recently used
+// one when maxProbeTargets is reached.
+func (s *targetStates) get(url string) *targetState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if s.states == nil {
+		s.states = map[string]*targetState{}
+	}
+	var oldest string
+	for u, st := range s.states {
+		if now.Sub(st.lastUsed) > probeTargetTTL {
+			delete(s.states, u)
+			continue
+		}
+		if oldest == "" || st.lastUsed.Before(s.states[oldest].lastUsed) {
+			oldest = u
+		}
+	}
+
+	st, ok := s.states[url]
+	if !ok {
+		if len(s.states) >= maxProbeTargets {
+			delete(s.states, oldest)
+		}
+		st = &targetState{}
+		s.states[url] = st
+	}
+	st.lastUsed = now
+	return st
+}
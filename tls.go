@@ -0,0 +1,55 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+var tlsVersions = map[string]uint16{
+	"TLS10": tls.VersionTLS10,
+	"TLS11": tls.VersionTLS11,
+	"TLS12": tls.VersionTLS12,
+	"TLS13": tls.VersionTLS13,
+}
+
+// newTLSConfig builds the TLS configuration of the JMX client. All arguments
+// are optional: caFile replaces the system roots, certFile and keyFile
+// present a client certificate, serverName overrides the name used to
+// verify the namenode certificate and minVersion is one of TLS10 to TLS13.
+func newTLSConfig(caFile, certFile, keyFile, serverName, minVersion string) (*tls.Config, error) {
+	config := &tls.Config{ServerName: serverName}
+
+	if caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("can't read CA file %q: %s", caFile, err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %q", caFile)
+		}
+	}
+
+	if (certFile == "") != (keyFile == "") {
+		return nil, fmt.Errorf("client certificate and key file must be given together")
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("can't load client certificate %q: %s", certFile, err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	if minVersion != "" {
+		version, ok := tlsVersions[strings.ToUpper(minVersion)]
+		if !ok {
+			return nil, fmt.Errorf("unknown TLS version %q", minVersion)
+		}
+		config.MinVersion = version
+	}
+	return config, nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const fsNamesystemStateBean = "Hadoop:service=NameNode,name=FSNamesystemState"
+
+// topUserOpsCollector maps the TopUserOpCounts attribute of
+// FSNamesystemState, a JSON document with the users issuing the most
+// operations of each type in rolling windows, 1, 5 and 25 minutes by default.
+// The operation "*" counts all operations.
+var topUserOpsCollector = &beanCollector{
+	bean:       fsNamesystemStateBean,
+	attributes: []string{"TopUserOpCounts"},
+	collect:    collectTopUserOps,
+}
+
+// topUserOpCounts is the content of the TopUserOpCounts attribute.
+type topUserOpCounts struct {
+	Windows []struct {
+		WindowLenMs int64 `json:"windowLenMs"`
+		Ops         []struct {
+			OpType   string `json:"opType"`
+			TopUsers []struct {
+				User  string  `json:"user"`
+				Count float64 `json:"count"`
+			} `json:"topUsers"`
+			TotalCount float64 `json:"totalCount"`
+		} `json:"ops"`
+	} `json:"windows"`
+}
+
+func collectTopUserOps(e *Exporter, bean jmxBean, ch chan<- prometheus.Metric) {
+	if v, ok := bean["TopUserOpCounts"]; ok && v == nil {
+		// nntop is disabled by dfs.namenode.top.enabled.
+		return
+	}
+	var top topUserOpCounts
+	if !bean.decodeJSON("TopUserOpCounts", &top) {
+		return
+	}
+
+	var (
+		userOps = prometheus.NewDesc(
+			prometheus.BuildFQName(e.module.name, "top", "user_ops"),
+			"Number of operations of the user in the window, for the top users of each operation.",
+			[]string{"window", "op", "user"},
+			e.constLabels,
+		)
+		ops = prometheus.NewDesc(
+			prometheus.BuildFQName(e.module.name, "top", "ops"),
+			"Number of operations of all users in the window.",
+			[]string{"window", "op"},
+			e.constLabels,
+		)
+	)
+	for _, w := range top.Windows {
+		window := windowLabel(w.WindowLenMs)
+		for _, op := range w.Ops {
+			ch <- prometheus.MustNewConstMetric(ops, prometheus.GaugeValue, op.TotalCount, window, op.OpType)
+			for _, u := range op.TopUsers {
+				ch <- prometheus.MustNewConstMetric(userOps, prometheus.GaugeValue, u.Count, window, op.OpType, u.User)
+			}
+		}
+	}
+}
+
+// windowLabel formats the length of a window, e.g. 300000 as "5m".
+func windowLabel(ms int64) string {
+	if ms%60000 == 0 {
+		return fmt.Sprintf("%dm", ms/60000)
+	}
+	return fmt.Sprintf("%ds", ms/1000)
+}
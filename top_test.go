@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func collectTestTopUserOps(t *testing.T, bean jmxBean) map[string]float64 {
+	e := &Exporter{module: modules["namenode"]}
+	return collectTestMetrics(t, func(ch chan<- prometheus.Metric) {
+		collectTopUserOps(e, bean, ch)
+	})
+}
+
+func TestCollectTopUserOps(t *testing.T) {
+	// As reported by a Hadoop 3.3 namenode with the default windows.
+	const top = `{"timestamp":"2024-03-01T10:15:42+0000","windows":[` +
+		`{"windowLenMs":300000,"ops":[` +
+		`{"opType":"listStatus","topUsers":[{"user":"hive","count":120},{"user":"spark","count":8}],"totalCount":128},` +
+		`{"opType":"*","topUsers":[{"user":"hive","count":130},{"user":"spark","count":9}],"totalCount":139}]},` +
+		`{"windowLenMs":1500000,"ops":[` +
+		`{"opType":"create","topUsers":[{"user":"spark","count":3}],"totalCount":3}]},` +
+		`{"windowLenMs":60000,"ops":[]}]}`
+	got := collectTestTopUserOps(t, jmxBean{"name": fsNamesystemStateBean, "TopUserOpCounts": top})
+	want := map[string]float64{
+		`namenode_top_ops{op="listStatus",window="5m"}`:                   128,
+		`namenode_top_user_ops{op="listStatus",user="hive",window="5m"}`:  120,
+		`namenode_top_user_ops{op="listStatus",user="spark",window="5m"}`: 8,
+		`namenode_top_ops{op="*",window="5m"}`:                            139,
+		`namenode_top_user_ops{op="*",user="hive",window="5m"}`:           130,
+		`namenode_top_user_ops{op="*",user="spark",window="5m"}`:          9,
+		`namenode_top_ops{op="create",window="25m"}`:                      3,
+		`namenode_top_user_ops{op="create",user="spark",window="25m"}`:    3,
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestCollectTopUserOpsEmpty(t *testing.T) {
+	const bean = "Hadoop:service=NameNode,name=TestCollectTopUserOpsEmpty"
+	errorCount := func() float64 {
+		var n float64
+		for _, reason := range []string{reasonNull, reasonInvalidType, reasonInvalidValue} {
+			n += attributeErrorCount(bean, "TopUserOpCounts", reason)
+		}
+		return n
+	}
+	for _, value := range []interface{}{
+		`{"timestamp":"2024-03-01T10:15:42+0000","windows":[]}`,
+		`{"timestamp":"2024-03-01T10:15:42+0000","windows":[{"windowLenMs":60000,"ops":[]}]}`,
+		// nntop is disabled.
+		nil,
+	} {
+		before := errorCount()
+		if got := collectTestTopUserOps(t, jmxBean{"name": bean, "TopUserOpCounts": value}); len(got) != 0 {
+			t.Errorf("%v: got %v, want no metrics", value, got)
+		}
+		if n := errorCount() - before; n != 0 {
+			t.Errorf("%v: counted %v attribute errors, want none", value, n)
+		}
+	}
+}
+
+func TestCollectTopUserOpsInvalid(t *testing.T) {
+	const bean = "Hadoop:service=NameNode,name=TestCollectTopUserOpsInvalid"
+	for _, tc := range []struct {
+		value  interface{}
+		reason string
+	}{
+		{`{"windows":[{"windowLenMs":60000,"ops":[`, reasonInvalidValue},
+		{`{"windows":{}}`, reasonInvalidValue},
+		{map[string]interface{}{"windows": []interface{}{}}, reasonInvalidType},
+	} {
+		before := attributeErrorCount(bean, "TopUserOpCounts", tc.reason)
+		if got := collectTestTopUserOps(t, jmxBean{"name": bean, "TopUserOpCounts": tc.value}); len(got) != 0 {
+			t.Errorf("%v: got %v, want no metrics", tc.value, got)
+		}
+		if n := attributeErrorCount(bean, "TopUserOpCounts", tc.reason) - before; n != 1 {
+			t.Errorf("%v: counted %v errors with reason %q, want 1", tc.value, n, tc.reason)
+		}
+	}
+}
+
+func TestWindowLabel(t *testing.T) {
+	for ms, want := range map[int64]string{60000: "1m", 300000: "5m", 1500000: "25m", 90000: "90s", 10000: "10s"} {
+		if got := windowLabel(ms); got != want {
+			t.Errorf("%d: got %q, want %q", ms, got, want)
+		}
+	}
+}